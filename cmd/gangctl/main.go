@@ -0,0 +1,13 @@
+package main
+
+import (
+	"os"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/gangctl"
+)
+
+func main() {
+	if err := gangctl.NewCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
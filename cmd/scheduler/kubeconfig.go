@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	componentbaseconfig "k8s.io/component-base/config"
+	"k8s.io/kubernetes/cmd/kube-scheduler/app/options"
+	kubeschedulerconfig "k8s.io/kubernetes/pkg/scheduler/apis/config"
+	kubeschedulerscheme "k8s.io/kubernetes/pkg/scheduler/apis/config/scheme"
+)
+
+// kubeConfig returns the config kube-scheduler makes its clients with when
+// run with args, for the plugins to make theirs: the clientConnection of the
+// --config file, or of the deprecated flags without one, and --master. It
+// follows options.Options.Config, which does not expose it.
+func kubeConfig(args []string) (*rest.Config, error) {
+	opts, err := options.NewOptions()
+	if err != nil {
+		return nil, err
+	}
+	fs := pflag.NewFlagSet("kube-scheduler", pflag.ContinueOnError)
+	// The command parses the flags first, and fails on the ones it does not
+	// know.
+	fs.ParseErrorsWhitelist.UnknownFlags = true
+	for _, f := range opts.Flags().FlagSets {
+		fs.AddFlagSet(f)
+	}
+	if err := fs.Parse(args); err != nil && err != pflag.ErrHelp {
+		return nil, err
+	}
+
+	clientConnection := opts.ComponentConfig.ClientConnection
+	if opts.ConfigFile != "" {
+		if clientConnection, err = loadClientConnection(opts.ConfigFile); err != nil {
+			return nil, err
+		}
+	}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: clientConnection.Kubeconfig},
+		&clientcmd.ConfigOverrides{ClusterInfo: clientcmdapi.Cluster{Server: opts.Master}}).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.DisableCompression = true
+	config.AcceptContentTypes = clientConnection.AcceptContentTypes
+	config.ContentType = clientConnection.ContentType
+	config.QPS = clientConnection.QPS
+	config.Burst = int(clientConnection.Burst)
+	return config, nil
+}
+
+// loadClientConnection returns the clientConnection of the scheduler
+// configuration file, defaulted.
+func loadClientConnection(file string) (componentbaseconfig.ClientConnectionConfiguration, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return componentbaseconfig.ClientConnectionConfiguration{}, err
+	}
+	obj, gvk, err := kubeschedulerscheme.Codecs.UniversalDecoder().Decode(data, nil, nil)
+	if err != nil {
+		return componentbaseconfig.ClientConnectionConfiguration{}, err
+	}
+	cfg, ok := obj.(*kubeschedulerconfig.KubeSchedulerConfiguration)
+	if !ok {
+		return componentbaseconfig.ClientConnectionConfiguration{}, fmt.Errorf("couldn't decode as KubeSchedulerConfiguration, got %s", gvk)
+	}
+	return cfg.ClientConnection, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestKubeConfig(t *testing.T) {
+	dir := t.TempDir()
+	kubeconfig := filepath.Join(dir, "kubeconfig")
+	if err := ioutil.WriteFile(kubeconfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: https://cluster:6443
+contexts:
+- name: context
+  context:
+    cluster: cluster
+current-context: context
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(configFile, []byte(`apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+leaderElection:
+  leaderElect: false
+clientConnection:
+  kubeconfig: `+kubeconfig+`
+  qps: 7
+  burst: 9
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		args   []string
+		host   string
+		qps    float32
+		burst  int
+		ctType string
+	}{
+		{name: "configuration file", args: []string{"--config", configFile, "--v=3"},
+			host: "https://cluster:6443", qps: 7, burst: 9, ctType: "application/vnd.kubernetes.protobuf"},
+		{name: "deprecated flags", args: []string{"--kubeconfig", kubeconfig, "--kube-api-qps", "11", "-v", "3"},
+			host: "https://cluster:6443", qps: 11, burst: 100, ctType: "application/vnd.kubernetes.protobuf"},
+		{name: "master overrides the kubeconfig", args: []string{"--config", configFile, "--master", "https://master:6443"},
+			host: "https://master:6443", qps: 7, burst: 9, ctType: "application/vnd.kubernetes.protobuf"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := kubeConfig(tt.args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.Host != tt.host || config.QPS != tt.qps || config.Burst != tt.burst || config.ContentType != tt.ctType {
+				t.Errorf("expected %v with qps %v, burst %v and %v, got %v with qps %v, burst %v and %v",
+					tt.host, tt.qps, tt.burst, tt.ctType, config.Host, config.QPS, config.Burst, config.ContentType)
+			}
+		})
+	}
+}
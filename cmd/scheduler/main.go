@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	// Register the plugin args in the scheduler configuration scheme.
+	_ "github.com/FFFFFaraway/gang-scheduler/pkg/apis/config/scheme"
+	"github.com/FFFFFaraway/gang-scheduler/pkg/plugins/binpack"
 	"github.com/FFFFFaraway/gang-scheduler/pkg/plugins/sample"
+	"k8s.io/client-go/rest"
 	"k8s.io/component-base/logs"
 	"k8s.io/kubernetes/cmd/kube-scheduler/app"
 )
@@ -16,9 +23,20 @@ func main() {
 	logs.InitLogs()
 	defer logs.FlushLogs()
 
+	// The informers of the plugins stop with the scheduler.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// The plugins of every profile are served on one listener, started with
+	// the first of them once the flags are parsed.
+	debug := sample.NewDebugServer()
 	cmd := app.NewSchedulerCommand(
-		app.WithPlugin(sample.Name, sample.New),
+		app.WithPlugin(sample.Name, sample.NewFactory(func() (*rest.Config, error) {
+			return kubeConfig(os.Args[1:])
+		}, debug, ctx.Done())),
+		app.WithPlugin(binpack.Name, binpack.New),
 	)
+	cmd.Flags().StringVar(&debug.Address, "gang-debug-address", "",
+		"The address to serve the live state of the groups on at "+sample.DebugPath+", e.g. 127.0.0.1:10261. Nothing is served if empty.")
 
 	if err := cmd.Execute(); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
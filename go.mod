@@ -3,13 +3,20 @@ module github.com/FFFFFaraway/gang-scheduler
 go 1.16
 
 require (
+	github.com/google/go-cmp v0.5.4
+	github.com/spf13/cobra v1.1.1
+	github.com/spf13/pflag v1.0.5
 	k8s.io/api v0.21.4
 	k8s.io/apimachinery v0.21.4
+	k8s.io/apiserver v0.21.4
 	k8s.io/client-go v0.21.4
+	k8s.io/code-generator v0.21.4
 	k8s.io/component-base v0.21.4
 	k8s.io/component-helpers v0.21.4
 	k8s.io/klog/v2 v2.8.0
+	k8s.io/kube-scheduler v0.0.0
 	k8s.io/kubernetes v1.21.4
+	k8s.io/utils v0.0.0-20201110183641-67b214c5f920
 )
 
 replace (
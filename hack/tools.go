@@ -0,0 +1,9 @@
+// +build tools
+
+// Package tools tracks the code generators this repo depends on so that
+// "go mod tidy" keeps them in go.mod.
+package tools
+
+import (
+	_ "k8s.io/code-generator"
+)
@@ -0,0 +1,69 @@
+#!/usr/bin/env bash
+
+# Copyright 2022 The gang-scheduler Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+SCRIPT_ROOT=$(dirname "${BASH_SOURCE[0]}")/..
+MODULE=github.com/FFFFFaraway/gang-scheduler
+OUTPUT_BASE=$(mktemp -d)
+trap 'rm -rf "${OUTPUT_BASE}"' EXIT
+
+GOBIN="${OUTPUT_BASE}/bin" go install \
+  k8s.io/code-generator/cmd/{deepcopy-gen,conversion-gen,defaulter-gen,client-gen,lister-gen,informer-gen}
+GEN_BIN="${OUTPUT_BASE}/bin"
+
+COMMON_FLAGS=(--output-base "${OUTPUT_BASE}" --go-header-file "${SCRIPT_ROOT}/hack/boilerplate.go.txt")
+SCHEDULING_APIS="${MODULE}/pkg/apis/scheduling/v1alpha1"
+CONFIG_APIS="${MODULE}/pkg/apis/config"
+
+"${GEN_BIN}/deepcopy-gen" \
+  --input-dirs "${SCHEDULING_APIS},${CONFIG_APIS},${CONFIG_APIS}/v1beta1" \
+  -O zz_generated.deepcopy \
+  "${COMMON_FLAGS[@]}"
+
+"${GEN_BIN}/conversion-gen" \
+  --input-dirs "${CONFIG_APIS}/v1beta1" \
+  -O zz_generated.conversion \
+  "${COMMON_FLAGS[@]}"
+
+"${GEN_BIN}/defaulter-gen" \
+  --input-dirs "${CONFIG_APIS}/v1beta1" \
+  -O zz_generated.defaults \
+  "${COMMON_FLAGS[@]}"
+
+"${GEN_BIN}/client-gen" \
+  --clientset-name versioned \
+  --input-base "" \
+  --input "${SCHEDULING_APIS}" \
+  --output-package "${MODULE}/pkg/generated/clientset" \
+  "${COMMON_FLAGS[@]}"
+
+"${GEN_BIN}/lister-gen" \
+  --input-dirs "${SCHEDULING_APIS}" \
+  --output-package "${MODULE}/pkg/generated/listers" \
+  "${COMMON_FLAGS[@]}"
+
+"${GEN_BIN}/informer-gen" \
+  --input-dirs "${SCHEDULING_APIS}" \
+  --versioned-clientset-package "${MODULE}/pkg/generated/clientset/versioned" \
+  --listers-package "${MODULE}/pkg/generated/listers" \
+  --output-package "${MODULE}/pkg/generated/informers" \
+  "${COMMON_FLAGS[@]}"
+
+rm -rf "${SCRIPT_ROOT}/pkg/generated"
+cp -r "${OUTPUT_BASE}/${MODULE}/pkg/." "${SCRIPT_ROOT}/pkg/"
@@ -0,0 +1,5 @@
+// +k8s:deepcopy-gen=package
+
+// Package config contains the internal types of the arguments of the plugins
+// in this repo, registered in the kube-scheduler configuration scheme.
+package config
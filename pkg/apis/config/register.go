@@ -0,0 +1,36 @@
+package config
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	schedconfig "k8s.io/kubernetes/pkg/scheduler/apis/config"
+)
+
+// SchemeGroupVersion is group version used to register these objects
+var SchemeGroupVersion = schedconfig.SchemeGroupVersion
+
+// SampleArgsKind is the kind of SampleArgs. The scheduler looks the args of a
+// plugin up by the plugin name followed by "Args", and the sample plugin is
+// named in lower case.
+const SampleArgsKind = "sampleArgs"
+
+// BinpackArgsKind is the kind of BinpackArgs, named like SampleArgsKind.
+const BinpackArgsKind = "binpackArgs"
+
+var (
+	// localSchemeBuilder extends the kube-scheduler SchemeBuilder, so that the
+	// scheme the scheduler converts plugin args with knows these types too.
+	localSchemeBuilder = &schedconfig.SchemeBuilder
+	// AddToScheme is a global function that registers this API group & version to a scheme
+	AddToScheme = localSchemeBuilder.AddToScheme
+)
+
+// Adds the list of known types to Scheme.
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypeWithName(SchemeGroupVersion.WithKind(SampleArgsKind), &SampleArgs{})
+	scheme.AddKnownTypeWithName(SchemeGroupVersion.WithKind(BinpackArgsKind), &BinpackArgs{})
+	return nil
+}
+
+func init() {
+	localSchemeBuilder.Register(addKnownTypes)
+}
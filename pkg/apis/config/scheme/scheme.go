@@ -0,0 +1,27 @@
+package scheme
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	kubeschedulerscheme "k8s.io/kubernetes/pkg/scheduler/apis/config/scheme"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config"
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config/v1beta1"
+)
+
+var (
+	// Scheme is the kube-scheduler scheme, which the scheduler decodes its
+	// configuration with. Importing this package registers the plugin args
+	// in it.
+	Scheme = kubeschedulerscheme.Scheme
+)
+
+func init() {
+	AddToScheme(Scheme)
+}
+
+// AddToScheme registers the plugin args of all known versions in scheme.
+func AddToScheme(scheme *runtime.Scheme) {
+	utilruntime.Must(config.AddToScheme(scheme))
+	utilruntime.Must(v1beta1.AddToScheme(scheme))
+}
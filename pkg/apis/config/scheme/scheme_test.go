@@ -0,0 +1,146 @@
+package scheme
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	schedconfig "k8s.io/kubernetes/pkg/scheduler/apis/config"
+	kubeschedulerscheme "k8s.io/kubernetes/pkg/scheduler/apis/config/scheme"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config"
+)
+
+func TestDecodeSampleArgs(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		data     string
+		expected *config.SampleArgs
+	}{
+		{
+			name: "all args set",
+			data: `
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+profiles:
+- schedulerName: gang-scheduler
+  pluginConfig:
+  - name: sample
+    args:
+      defaultTimeoutSeconds: 30
+      maxTimeoutSeconds: 60
+      groupLabelKey: example.com/group
+      groupLabelKeys: []
+      groupAnnotationKeys:
+      - example.com/group-name
+      requireGroup: true
+      backoffInitialSeconds: 1
+      backoffMaxSeconds: 10
+      succeededMembers: Ignore
+      roleLabelKey: example.com/role
+      topologyKey: example.com/rack
+      queueLabelKey: example.com/queue
+`,
+			expected: &config.SampleArgs{
+				DefaultTimeoutSeconds: 30,
+				MaxTimeoutSeconds:     60,
+				GroupLabelKey:         "example.com/group",
+				GroupLabelKeys:        []string{},
+				GroupAnnotationKeys:   []string{"example.com/group-name"},
+				RequireGroup:          true,
+				BackoffInitialSeconds: 1,
+				BackoffMaxSeconds:     10,
+				SucceededMembers:      "Ignore",
+				RoleLabelKey:          "example.com/role",
+				TopologyKey:           "example.com/rack",
+				QueueLabelKey:         "example.com/queue",
+			},
+		},
+		{
+			name: "defaults",
+			data: `
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+profiles:
+- schedulerName: gang-scheduler
+  pluginConfig:
+  - name: sample
+    args:
+      requireGroup: true
+`,
+			expected: &config.SampleArgs{
+				DefaultTimeoutSeconds: 10,
+				MaxTimeoutSeconds:     600,
+				GroupLabelKey:         "pod-group.scheduling.bdap.com/podgroup-configmap",
+				GroupLabelKeys:        []string{"pod-group.scheduling.sigs.k8s.io"},
+				GroupAnnotationKeys:   []string{"scheduling.k8s.io/group-name"},
+				RequireGroup:          true,
+				BackoffInitialSeconds: 2,
+				BackoffMaxSeconds:     120,
+				SucceededMembers:      "Count",
+				RoleLabelKey:          "pod-group.scheduling.bdap.com/role",
+				TopologyKey:           "topology.kubernetes.io/zone",
+				QueueLabelKey:         "scheduling.bdap.com/queue",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, _, err := kubeschedulerscheme.Codecs.UniversalDecoder().Decode([]byte(tt.data), nil, nil)
+			if err != nil {
+				t.Fatalf("fail to decode: %v", err)
+			}
+			cfg, ok := obj.(*schedconfig.KubeSchedulerConfiguration)
+			if !ok {
+				t.Fatalf("expected KubeSchedulerConfiguration, got %T", obj)
+			}
+			got := cfg.Profiles[0].PluginConfig[0].Args
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("unexpected args (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeBinpackArgs(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		args     string
+		expected *config.BinpackArgs
+	}{
+		{
+			name: "resources set",
+			args: `
+    args:
+      resources:
+      - example.com/fpga
+      - nvidia.com/gpu`,
+			expected: &config.BinpackArgs{Resources: []string{"example.com/fpga", "nvidia.com/gpu"}},
+		},
+		{
+			name:     "defaults",
+			args:     "",
+			expected: &config.BinpackArgs{Resources: []string{"nvidia.com/gpu"}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data := `
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+profiles:
+- schedulerName: gang-scheduler
+  pluginConfig:
+  - name: binpack` + tt.args + "\n"
+			obj, _, err := kubeschedulerscheme.Codecs.UniversalDecoder().Decode([]byte(data), nil, nil)
+			if err != nil {
+				t.Fatalf("fail to decode: %v", err)
+			}
+			cfg, ok := obj.(*schedconfig.KubeSchedulerConfiguration)
+			if !ok {
+				t.Fatalf("expected KubeSchedulerConfiguration, got %T", obj)
+			}
+			got := cfg.Profiles[0].PluginConfig[0].Args
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("unexpected args (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// VolcanoPodGroups names the scheduling.volcano.sh/v1beta1 PodGroups in
+	// SampleArgs.ForeignPodGroups.
+	VolcanoPodGroups = "Volcano"
+	// SchedulerPluginsPodGroups names the scheduling.x-k8s.io/v1alpha1
+	// PodGroups of scheduler-plugins in SampleArgs.ForeignPodGroups.
+	SchedulerPluginsPodGroups = "SchedulerPlugins"
+
+	// SucceededMembersCount counts the succeeded members of a group towards
+	// its minAvailable, in SampleArgs.SucceededMembers.
+	SucceededMembersCount = "Count"
+	// SucceededMembersIgnore does not count the succeeded members of a group
+	// towards its minAvailable, in SampleArgs.SucceededMembers.
+	SucceededMembersIgnore = "Ignore"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SampleArgs holds the arguments used to configure the sample plugin.
+type SampleArgs struct {
+	metav1.TypeMeta
+
+	// DefaultTimeoutSeconds is how long the members of a group wait in Permit
+	// for their siblings when the group does not set scheduleTimeoutSeconds.
+	DefaultTimeoutSeconds int64
+	// MaxTimeoutSeconds caps the timeout a group can ask for.
+	MaxTimeoutSeconds int64
+	// GroupLabelKey is the pod label naming the group of a pod.
+	GroupLabelKey string
+	// GroupLabelKeys are other pod labels naming the group of a pod, looked
+	// at in order when the pod has no GroupLabelKey label.
+	GroupLabelKeys []string
+	// GroupAnnotationKeys are pod annotations naming the group of a pod,
+	// looked at in order when the pod has none of the labels.
+	GroupAnnotationKeys []string
+	// ForeignPodGroups are the PodGroup CRDs of other schedulers read as
+	// groups too, looked at in order after the PodGroups of this scheduler.
+	ForeignPodGroups []string
+	// RequireGroup rejects the pods that are not in a group.
+	RequireGroup bool
+	// BackoffInitialSeconds is how long a group that failed to assemble is
+	// kept out of the cluster the first time, doubled on each failure.
+	BackoffInitialSeconds int64
+	// BackoffMaxSeconds caps the back off of a group.
+	BackoffMaxSeconds int64
+	// SucceededMembers is whether the succeeded members of a group count
+	// towards its minAvailable.
+	SucceededMembers string
+	// RoleLabelKey is the pod label naming the role of a member, which the
+	// per-role minimums of a group are keyed by.
+	RoleLabelKey string
+	// TopologyKey is the node label naming the topology domain of a node,
+	// which the members of a group are packed in or spread across.
+	TopologyKey string
+	// QueueLabelKey is the pod label naming the queue of a pod, whose quota
+	// its gang is admitted against.
+	QueueLabelKey string
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BinpackArgs holds the arguments used to configure the binpack plugin.
+type BinpackArgs struct {
+	metav1.TypeMeta
+
+	// Resources are the extended resources packed onto the nodes already
+	// using them.
+	Resources []string
+}
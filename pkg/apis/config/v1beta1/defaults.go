@@ -0,0 +1,84 @@
+package v1beta1
+
+import (
+	"k8s.io/utils/pointer"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config"
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+)
+
+const (
+	// DefaultGroupLabelKey is the pod label naming the group of a pod unless
+	// SampleArgs sets another one.
+	DefaultGroupLabelKey = v1alpha1.PodGroupLabel
+	// DefaultRoleLabelKey is the pod label naming the role of a member unless
+	// SampleArgs sets another one.
+	DefaultRoleLabelKey = v1alpha1.RoleLabel
+	// DefaultTopologyKey is the node label naming the topology domain of a
+	// node unless SampleArgs sets another one.
+	DefaultTopologyKey = "topology.kubernetes.io/zone"
+	// DefaultQueueLabelKey is the pod label naming the queue of a pod unless
+	// SampleArgs sets another one.
+	DefaultQueueLabelKey = "scheduling.bdap.com/queue"
+	// DefaultBinpackResource is the extended resource packed unless
+	// BinpackArgs sets others.
+	DefaultBinpackResource = "nvidia.com/gpu"
+	// CoschedulingGroupLabelKey is the pod label naming the group of a pod in
+	// the coscheduling plugin of scheduler-plugins.
+	CoschedulingGroupLabelKey = "pod-group.scheduling.sigs.k8s.io"
+	// VolcanoGroupAnnotationKey is the pod annotation naming the group of a
+	// pod in Volcano.
+	VolcanoGroupAnnotationKey = "scheduling.k8s.io/group-name"
+
+	defaultTimeoutSeconds        int64 = 10
+	defaultMaxTimeoutSeconds     int64 = 600
+	defaultBackoffInitialSeconds int64 = 2
+	defaultBackoffMaxSeconds     int64 = 120
+)
+
+// SetDefaults_SampleArgs sets the default parameters for the sample plugin.
+func SetDefaults_SampleArgs(obj *SampleArgs) {
+	if obj.DefaultTimeoutSeconds == nil {
+		obj.DefaultTimeoutSeconds = pointer.Int64Ptr(defaultTimeoutSeconds)
+	}
+	if obj.MaxTimeoutSeconds == nil {
+		obj.MaxTimeoutSeconds = pointer.Int64Ptr(defaultMaxTimeoutSeconds)
+	}
+	if obj.GroupLabelKey == nil {
+		obj.GroupLabelKey = pointer.StringPtr(DefaultGroupLabelKey)
+	}
+	if obj.GroupLabelKeys == nil {
+		obj.GroupLabelKeys = []string{CoschedulingGroupLabelKey}
+	}
+	if obj.GroupAnnotationKeys == nil {
+		obj.GroupAnnotationKeys = []string{VolcanoGroupAnnotationKey}
+	}
+	if obj.RequireGroup == nil {
+		obj.RequireGroup = pointer.BoolPtr(false)
+	}
+	if obj.BackoffInitialSeconds == nil {
+		obj.BackoffInitialSeconds = pointer.Int64Ptr(defaultBackoffInitialSeconds)
+	}
+	if obj.BackoffMaxSeconds == nil {
+		obj.BackoffMaxSeconds = pointer.Int64Ptr(defaultBackoffMaxSeconds)
+	}
+	if obj.SucceededMembers == nil {
+		obj.SucceededMembers = pointer.StringPtr(config.SucceededMembersCount)
+	}
+	if obj.RoleLabelKey == nil {
+		obj.RoleLabelKey = pointer.StringPtr(DefaultRoleLabelKey)
+	}
+	if obj.TopologyKey == nil {
+		obj.TopologyKey = pointer.StringPtr(DefaultTopologyKey)
+	}
+	if obj.QueueLabelKey == nil {
+		obj.QueueLabelKey = pointer.StringPtr(DefaultQueueLabelKey)
+	}
+}
+
+// SetDefaults_BinpackArgs sets the default parameters for the binpack plugin.
+func SetDefaults_BinpackArgs(obj *BinpackArgs) {
+	if obj.Resources == nil {
+		obj.Resources = []string{DefaultBinpackResource}
+	}
+}
@@ -0,0 +1,8 @@
+// +k8s:deepcopy-gen=package
+// +k8s:conversion-gen=github.com/FFFFFaraway/gang-scheduler/pkg/apis/config
+// +k8s:defaulter-gen=TypeMeta
+// +groupName=kubescheduler.config.k8s.io
+
+// Package v1beta1 is the v1beta1 version of the plugin args, read from the
+// pluginConfig of a kubescheduler.config.k8s.io/v1beta1 profile.
+package v1beta1
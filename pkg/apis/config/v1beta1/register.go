@@ -0,0 +1,35 @@
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	schedconfigv1beta1 "k8s.io/kube-scheduler/config/v1beta1"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config"
+)
+
+// GroupName is the group name used in this package
+const GroupName = schedconfigv1beta1.GroupName
+
+// SchemeGroupVersion is group version used to register these objects
+var SchemeGroupVersion = schedconfigv1beta1.SchemeGroupVersion
+
+var (
+	// localSchemeBuilder extends the kube-scheduler v1beta1 SchemeBuilder, so
+	// that the scheme the scheduler defaults plugin args with knows these
+	// types too. Conversion funcs are registered by the generated files.
+	localSchemeBuilder = &schedconfigv1beta1.SchemeBuilder
+	// AddToScheme is a global function that registers this API group & version to a scheme
+	AddToScheme = localSchemeBuilder.AddToScheme
+)
+
+// Adds the list of known types to Scheme.
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypeWithName(SchemeGroupVersion.WithKind(config.SampleArgsKind), &SampleArgs{})
+	scheme.AddKnownTypeWithName(SchemeGroupVersion.WithKind(config.BinpackArgsKind), &BinpackArgs{})
+	return nil
+}
+
+func init() {
+	localSchemeBuilder.Register(addKnownTypes)
+	localSchemeBuilder.Register(RegisterDefaults)
+}
@@ -0,0 +1,79 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SampleArgs holds the arguments used to configure the sample plugin.
+type SampleArgs struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// DefaultTimeoutSeconds is how long the members of a group wait in Permit
+	// for their siblings when the group does not set scheduleTimeoutSeconds.
+	// Defaults to 10.
+	DefaultTimeoutSeconds *int64 `json:"defaultTimeoutSeconds,omitempty"`
+	// MaxTimeoutSeconds caps the timeout a group can ask for. Defaults to 600.
+	MaxTimeoutSeconds *int64 `json:"maxTimeoutSeconds,omitempty"`
+	// GroupLabelKey is the pod label naming the group of a pod. Defaults to
+	// pod-group.scheduling.bdap.com/podgroup-configmap.
+	GroupLabelKey *string `json:"groupLabelKey,omitempty"`
+	// GroupLabelKeys are other pod labels naming the group of a pod, looked
+	// at in order when the pod has no GroupLabelKey label. Defaults to the
+	// coscheduling label pod-group.scheduling.sigs.k8s.io, set it to an empty
+	// list to only use GroupLabelKey.
+	GroupLabelKeys []string `json:"groupLabelKeys,omitempty"`
+	// GroupAnnotationKeys are pod annotations naming the group of a pod,
+	// looked at in order when the pod has none of the labels. Defaults to the
+	// Volcano annotation scheduling.k8s.io/group-name, set it to an empty list
+	// to ignore annotations.
+	GroupAnnotationKeys []string `json:"groupAnnotationKeys,omitempty"`
+	// ForeignPodGroups are the PodGroup CRDs of other schedulers read as
+	// groups too, looked at in order after the PodGroups of this scheduler and
+	// before the legacy ConfigMaps: "Volcano" for
+	// scheduling.volcano.sh/v1beta1 and "SchedulerPlugins" for
+	// scheduling.x-k8s.io/v1alpha1. Their spec.minMember is the minAvailable
+	// of the group. Defaults to none.
+	ForeignPodGroups []string `json:"foreignPodGroups,omitempty"`
+	// RequireGroup rejects the pods that are not in a group, for profiles
+	// that only schedule gangs. Defaults to false.
+	RequireGroup *bool `json:"requireGroup,omitempty"`
+	// BackoffInitialSeconds is how long a group that failed to assemble is
+	// kept out of the cluster the first time, doubled on each failure.
+	// Defaults to 2.
+	BackoffInitialSeconds *int64 `json:"backoffInitialSeconds,omitempty"`
+	// BackoffMaxSeconds caps the back off of a group. Defaults to 120.
+	BackoffMaxSeconds *int64 `json:"backoffMaxSeconds,omitempty"`
+	// SucceededMembers is whether the succeeded members of a group count
+	// towards its minAvailable: "Count" for groups whose members may finish
+	// one by one, such as Jobs, or "Ignore" for groups needing all their
+	// members to run together. Defaults to "Count".
+	SucceededMembers *string `json:"succeededMembers,omitempty"`
+	// RoleLabelKey is the pod label naming the role of a member, which the
+	// per-role minimums of a group are keyed by, e.g. ps or worker. Defaults
+	// to pod-group.scheduling.bdap.com/role.
+	RoleLabelKey *string `json:"roleLabelKey,omitempty"`
+	// TopologyKey is the node label naming the topology domain of a node,
+	// such as a zone or a rack, which the members of a group are packed in
+	// or spread across depending on the placement of the group. A node
+	// without the label is a domain of its own. Defaults to
+	// topology.kubernetes.io/zone.
+	TopologyKey *string `json:"topologyKey,omitempty"`
+	// QueueLabelKey is the pod label naming the Queue of a pod, whose quota
+	// its gang is admitted against. A pod without the label is in the Queue
+	// named like its namespace. Defaults to scheduling.bdap.com/queue.
+	QueueLabelKey *string `json:"queueLabelKey,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BinpackArgs holds the arguments used to configure the binpack plugin.
+type BinpackArgs struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Resources are the extended resources packed onto the nodes already
+	// using them, so that whole nodes are left free for the pods asking for
+	// a lot of them. Defaults to nvidia.com/gpu.
+	Resources []string `json:"resources,omitempty"`
+}
@@ -0,0 +1,165 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+/*
+Copyright 2022 The gang-scheduler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by conversion-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	unsafe "unsafe"
+
+	config "github.com/FFFFFaraway/gang-scheduler/pkg/apis/config"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	conversion "k8s.io/apimachinery/pkg/conversion"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	localSchemeBuilder.Register(RegisterConversions)
+}
+
+// RegisterConversions adds conversion functions to the given scheme.
+// Public to allow building arbitrary schemes.
+func RegisterConversions(s *runtime.Scheme) error {
+	if err := s.AddGeneratedConversionFunc((*BinpackArgs)(nil), (*config.BinpackArgs)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_BinpackArgs_To_config_BinpackArgs(a.(*BinpackArgs), b.(*config.BinpackArgs), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*config.BinpackArgs)(nil), (*BinpackArgs)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_config_BinpackArgs_To_v1beta1_BinpackArgs(a.(*config.BinpackArgs), b.(*BinpackArgs), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*SampleArgs)(nil), (*config.SampleArgs)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_SampleArgs_To_config_SampleArgs(a.(*SampleArgs), b.(*config.SampleArgs), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*config.SampleArgs)(nil), (*SampleArgs)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_config_SampleArgs_To_v1beta1_SampleArgs(a.(*config.SampleArgs), b.(*SampleArgs), scope)
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func autoConvert_v1beta1_BinpackArgs_To_config_BinpackArgs(in *BinpackArgs, out *config.BinpackArgs, s conversion.Scope) error {
+	out.Resources = *(*[]string)(unsafe.Pointer(&in.Resources))
+	return nil
+}
+
+// Convert_v1beta1_BinpackArgs_To_config_BinpackArgs is an autogenerated conversion function.
+func Convert_v1beta1_BinpackArgs_To_config_BinpackArgs(in *BinpackArgs, out *config.BinpackArgs, s conversion.Scope) error {
+	return autoConvert_v1beta1_BinpackArgs_To_config_BinpackArgs(in, out, s)
+}
+
+func autoConvert_config_BinpackArgs_To_v1beta1_BinpackArgs(in *config.BinpackArgs, out *BinpackArgs, s conversion.Scope) error {
+	out.Resources = *(*[]string)(unsafe.Pointer(&in.Resources))
+	return nil
+}
+
+// Convert_config_BinpackArgs_To_v1beta1_BinpackArgs is an autogenerated conversion function.
+func Convert_config_BinpackArgs_To_v1beta1_BinpackArgs(in *config.BinpackArgs, out *BinpackArgs, s conversion.Scope) error {
+	return autoConvert_config_BinpackArgs_To_v1beta1_BinpackArgs(in, out, s)
+}
+
+func autoConvert_v1beta1_SampleArgs_To_config_SampleArgs(in *SampleArgs, out *config.SampleArgs, s conversion.Scope) error {
+	if err := v1.Convert_Pointer_int64_To_int64(&in.DefaultTimeoutSeconds, &out.DefaultTimeoutSeconds, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_int64_To_int64(&in.MaxTimeoutSeconds, &out.MaxTimeoutSeconds, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_string_To_string(&in.GroupLabelKey, &out.GroupLabelKey, s); err != nil {
+		return err
+	}
+	out.GroupLabelKeys = *(*[]string)(unsafe.Pointer(&in.GroupLabelKeys))
+	out.GroupAnnotationKeys = *(*[]string)(unsafe.Pointer(&in.GroupAnnotationKeys))
+	out.ForeignPodGroups = *(*[]string)(unsafe.Pointer(&in.ForeignPodGroups))
+	if err := v1.Convert_Pointer_bool_To_bool(&in.RequireGroup, &out.RequireGroup, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_int64_To_int64(&in.BackoffInitialSeconds, &out.BackoffInitialSeconds, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_int64_To_int64(&in.BackoffMaxSeconds, &out.BackoffMaxSeconds, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_string_To_string(&in.SucceededMembers, &out.SucceededMembers, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_string_To_string(&in.RoleLabelKey, &out.RoleLabelKey, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_string_To_string(&in.TopologyKey, &out.TopologyKey, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_string_To_string(&in.QueueLabelKey, &out.QueueLabelKey, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_v1beta1_SampleArgs_To_config_SampleArgs is an autogenerated conversion function.
+func Convert_v1beta1_SampleArgs_To_config_SampleArgs(in *SampleArgs, out *config.SampleArgs, s conversion.Scope) error {
+	return autoConvert_v1beta1_SampleArgs_To_config_SampleArgs(in, out, s)
+}
+
+func autoConvert_config_SampleArgs_To_v1beta1_SampleArgs(in *config.SampleArgs, out *SampleArgs, s conversion.Scope) error {
+	if err := v1.Convert_int64_To_Pointer_int64(&in.DefaultTimeoutSeconds, &out.DefaultTimeoutSeconds, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_int64_To_Pointer_int64(&in.MaxTimeoutSeconds, &out.MaxTimeoutSeconds, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_string_To_Pointer_string(&in.GroupLabelKey, &out.GroupLabelKey, s); err != nil {
+		return err
+	}
+	out.GroupLabelKeys = *(*[]string)(unsafe.Pointer(&in.GroupLabelKeys))
+	out.GroupAnnotationKeys = *(*[]string)(unsafe.Pointer(&in.GroupAnnotationKeys))
+	out.ForeignPodGroups = *(*[]string)(unsafe.Pointer(&in.ForeignPodGroups))
+	if err := v1.Convert_bool_To_Pointer_bool(&in.RequireGroup, &out.RequireGroup, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_int64_To_Pointer_int64(&in.BackoffInitialSeconds, &out.BackoffInitialSeconds, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_int64_To_Pointer_int64(&in.BackoffMaxSeconds, &out.BackoffMaxSeconds, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_string_To_Pointer_string(&in.SucceededMembers, &out.SucceededMembers, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_string_To_Pointer_string(&in.RoleLabelKey, &out.RoleLabelKey, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_string_To_Pointer_string(&in.TopologyKey, &out.TopologyKey, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_string_To_Pointer_string(&in.QueueLabelKey, &out.QueueLabelKey, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_config_SampleArgs_To_v1beta1_SampleArgs is an autogenerated conversion function.
+func Convert_config_SampleArgs_To_v1beta1_SampleArgs(in *config.SampleArgs, out *SampleArgs, s conversion.Scope) error {
+	return autoConvert_config_SampleArgs_To_v1beta1_SampleArgs(in, out, s)
+}
@@ -0,0 +1,146 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+/*
+Copyright 2022 The gang-scheduler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by deepcopy-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BinpackArgs) DeepCopyInto(out *BinpackArgs) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BinpackArgs.
+func (in *BinpackArgs) DeepCopy() *BinpackArgs {
+	if in == nil {
+		return nil
+	}
+	out := new(BinpackArgs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BinpackArgs) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SampleArgs) DeepCopyInto(out *SampleArgs) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	if in.DefaultTimeoutSeconds != nil {
+		in, out := &in.DefaultTimeoutSeconds, &out.DefaultTimeoutSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxTimeoutSeconds != nil {
+		in, out := &in.MaxTimeoutSeconds, &out.MaxTimeoutSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.GroupLabelKey != nil {
+		in, out := &in.GroupLabelKey, &out.GroupLabelKey
+		*out = new(string)
+		**out = **in
+	}
+	if in.GroupLabelKeys != nil {
+		in, out := &in.GroupLabelKeys, &out.GroupLabelKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.GroupAnnotationKeys != nil {
+		in, out := &in.GroupAnnotationKeys, &out.GroupAnnotationKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ForeignPodGroups != nil {
+		in, out := &in.ForeignPodGroups, &out.ForeignPodGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequireGroup != nil {
+		in, out := &in.RequireGroup, &out.RequireGroup
+		*out = new(bool)
+		**out = **in
+	}
+	if in.BackoffInitialSeconds != nil {
+		in, out := &in.BackoffInitialSeconds, &out.BackoffInitialSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.BackoffMaxSeconds != nil {
+		in, out := &in.BackoffMaxSeconds, &out.BackoffMaxSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.SucceededMembers != nil {
+		in, out := &in.SucceededMembers, &out.SucceededMembers
+		*out = new(string)
+		**out = **in
+	}
+	if in.RoleLabelKey != nil {
+		in, out := &in.RoleLabelKey, &out.RoleLabelKey
+		*out = new(string)
+		**out = **in
+	}
+	if in.TopologyKey != nil {
+		in, out := &in.TopologyKey, &out.TopologyKey
+		*out = new(string)
+		**out = **in
+	}
+	if in.QueueLabelKey != nil {
+		in, out := &in.QueueLabelKey, &out.QueueLabelKey
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SampleArgs.
+func (in *SampleArgs) DeepCopy() *SampleArgs {
+	if in == nil {
+		return nil
+	}
+	out := new(SampleArgs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SampleArgs) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package validation
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	v1helper "k8s.io/kubernetes/pkg/apis/core/v1/helper"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config"
+)
+
+var (
+	validForeignPodGroups = sets.NewString(config.VolcanoPodGroups, config.SchedulerPluginsPodGroups)
+	validSucceededMembers = sets.NewString(config.SucceededMembersCount, config.SucceededMembersIgnore)
+)
+
+// ValidateSampleArgs validates the arguments of the sample plugin.
+func ValidateSampleArgs(args *config.SampleArgs) error {
+	var allErrs field.ErrorList
+	if args.DefaultTimeoutSeconds <= 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("defaultTimeoutSeconds"), args.DefaultTimeoutSeconds, "must be greater than 0"))
+	}
+	if args.MaxTimeoutSeconds < args.DefaultTimeoutSeconds {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("maxTimeoutSeconds"), args.MaxTimeoutSeconds, "must not be less than defaultTimeoutSeconds"))
+	}
+	allErrs = append(allErrs, validateKey(field.NewPath("groupLabelKey"), args.GroupLabelKey)...)
+	for i, key := range args.GroupLabelKeys {
+		allErrs = append(allErrs, validateKey(field.NewPath("groupLabelKeys").Index(i), key)...)
+	}
+	for i, key := range args.GroupAnnotationKeys {
+		allErrs = append(allErrs, validateKey(field.NewPath("groupAnnotationKeys").Index(i), key)...)
+	}
+	seen := sets.NewString()
+	for i, name := range args.ForeignPodGroups {
+		path := field.NewPath("foreignPodGroups").Index(i)
+		if !validForeignPodGroups.Has(name) {
+			allErrs = append(allErrs, field.NotSupported(path, name, validForeignPodGroups.List()))
+		} else if seen.Has(name) {
+			allErrs = append(allErrs, field.Duplicate(path, name))
+		}
+		seen.Insert(name)
+	}
+	if args.BackoffInitialSeconds <= 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("backoffInitialSeconds"), args.BackoffInitialSeconds, "must be greater than 0"))
+	}
+	if args.BackoffMaxSeconds < args.BackoffInitialSeconds {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("backoffMaxSeconds"), args.BackoffMaxSeconds, "must not be less than backoffInitialSeconds"))
+	}
+	if !validSucceededMembers.Has(args.SucceededMembers) {
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("succeededMembers"), args.SucceededMembers, validSucceededMembers.List()))
+	}
+	allErrs = append(allErrs, validateKey(field.NewPath("roleLabelKey"), args.RoleLabelKey)...)
+	allErrs = append(allErrs, validateKey(field.NewPath("topologyKey"), args.TopologyKey)...)
+	allErrs = append(allErrs, validateKey(field.NewPath("queueLabelKey"), args.QueueLabelKey)...)
+	return allErrs.ToAggregate()
+}
+
+// ValidateBinpackArgs validates the arguments of the binpack plugin.
+func ValidateBinpackArgs(args *config.BinpackArgs) error {
+	var allErrs field.ErrorList
+	if len(args.Resources) == 0 {
+		allErrs = append(allErrs, field.Required(field.NewPath("resources"), "must name at least one extended resource"))
+	}
+	seen := sets.NewString()
+	for i, name := range args.Resources {
+		path := field.NewPath("resources").Index(i)
+		if !v1helper.IsExtendedResourceName(v1.ResourceName(name)) {
+			allErrs = append(allErrs, field.Invalid(path, name, "must be an extended resource name"))
+		} else if seen.Has(name) {
+			allErrs = append(allErrs, field.Duplicate(path, name))
+		}
+		seen.Insert(name)
+	}
+	return allErrs.ToAggregate()
+}
+
+func validateKey(path *field.Path, key string) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, msg := range validation.IsQualifiedName(key) {
+		allErrs = append(allErrs, field.Invalid(path, key, msg))
+	}
+	return allErrs
+}
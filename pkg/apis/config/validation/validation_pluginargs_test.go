@@ -0,0 +1,77 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config"
+)
+
+func TestValidateSampleArgs(t *testing.T) {
+	valid := func() *config.SampleArgs {
+		return &config.SampleArgs{
+			DefaultTimeoutSeconds: 10,
+			MaxTimeoutSeconds:     600,
+			GroupLabelKey:         "pod-group.scheduling.bdap.com/podgroup-configmap",
+			BackoffInitialSeconds: 2,
+			BackoffMaxSeconds:     120,
+			SucceededMembers:      config.SucceededMembersCount,
+			RoleLabelKey:          "pod-group.scheduling.bdap.com/role",
+			TopologyKey:           "topology.kubernetes.io/zone",
+			QueueLabelKey:         "scheduling.bdap.com/queue",
+		}
+	}
+	for _, tt := range []struct {
+		name    string
+		modify  func(*config.SampleArgs)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(*config.SampleArgs) {}},
+		{name: "zero default timeout", modify: func(a *config.SampleArgs) { a.DefaultTimeoutSeconds = 0 }, wantErr: true},
+		{name: "max timeout below default", modify: func(a *config.SampleArgs) { a.MaxTimeoutSeconds = 5 }, wantErr: true},
+		{name: "empty label key", modify: func(a *config.SampleArgs) { a.GroupLabelKey = "" }, wantErr: true},
+		{name: "invalid label key", modify: func(a *config.SampleArgs) { a.GroupLabelKey = "a/b/c" }, wantErr: true},
+		{name: "invalid compat label key", modify: func(a *config.SampleArgs) { a.GroupLabelKeys = []string{"-bad"} }, wantErr: true},
+		{name: "invalid annotation key", modify: func(a *config.SampleArgs) { a.GroupAnnotationKeys = []string{""} }, wantErr: true},
+		{name: "foreign podgroups", modify: func(a *config.SampleArgs) {
+			a.ForeignPodGroups = []string{config.VolcanoPodGroups, config.SchedulerPluginsPodGroups}
+		}},
+		{name: "unknown foreign podgroups", modify: func(a *config.SampleArgs) { a.ForeignPodGroups = []string{"Yunikorn"} }, wantErr: true},
+		{name: "duplicated foreign podgroups", modify: func(a *config.SampleArgs) {
+			a.ForeignPodGroups = []string{config.VolcanoPodGroups, config.VolcanoPodGroups}
+		}, wantErr: true},
+		{name: "zero initial backoff", modify: func(a *config.SampleArgs) { a.BackoffInitialSeconds = 0 }, wantErr: true},
+		{name: "max backoff below initial", modify: func(a *config.SampleArgs) { a.BackoffMaxSeconds = 1 }, wantErr: true},
+		{name: "ignore succeeded members", modify: func(a *config.SampleArgs) { a.SucceededMembers = config.SucceededMembersIgnore }},
+		{name: "unknown succeeded members", modify: func(a *config.SampleArgs) { a.SucceededMembers = "Skip" }, wantErr: true},
+		{name: "empty role label key", modify: func(a *config.SampleArgs) { a.RoleLabelKey = "" }, wantErr: true},
+		{name: "invalid topology key", modify: func(a *config.SampleArgs) { a.TopologyKey = "a/b/c" }, wantErr: true},
+		{name: "empty queue label key", modify: func(a *config.SampleArgs) { a.QueueLabelKey = "" }, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			args := valid()
+			tt.modify(args)
+			if err := ValidateSampleArgs(args); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateBinpackArgs(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		resources []string
+		wantErr   bool
+	}{
+		{name: "valid", resources: []string{"nvidia.com/gpu", "example.com/fpga"}},
+		{name: "no resources", resources: []string{}, wantErr: true},
+		{name: "native resource", resources: []string{"cpu"}, wantErr: true},
+		{name: "duplicated resources", resources: []string{"nvidia.com/gpu", "nvidia.com/gpu"}, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateBinpackArgs(&config.BinpackArgs{Resources: tt.resources}); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
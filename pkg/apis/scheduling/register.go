@@ -0,0 +1,4 @@
+package scheduling
+
+// GroupName is the group name used in this package
+const GroupName = "scheduling.bdap.com"
@@ -0,0 +1,5 @@
+// +k8s:deepcopy-gen=package
+// +groupName=scheduling.bdap.com
+
+// Package v1alpha1 is the v1alpha1 version of the scheduling.bdap.com API.
+package v1alpha1
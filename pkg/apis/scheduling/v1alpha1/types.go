@@ -0,0 +1,194 @@
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodGroupPhase is the phase of a pod group at the current time.
+type PodGroupPhase string
+
+const (
+	// PodGroupPending means the pod group has been accepted by the system,
+	// but not enough members have been scheduled yet.
+	PodGroupPending PodGroupPhase = "Pending"
+	// PodGroupWaiting means some members are held in Permit waiting for their siblings.
+	PodGroupWaiting PodGroupPhase = "Waiting"
+	// PodGroupScheduled means minAvailable members have been admitted or bound,
+	// but not all of them are running yet.
+	PodGroupScheduled PodGroupPhase = "Scheduled"
+	// PodGroupRunning means at least minAvailable members are running.
+	PodGroupRunning PodGroupPhase = "Running"
+	// PodGroupFailed means some members failed and the group can not reach minAvailable anymore.
+	PodGroupFailed PodGroupPhase = "Failed"
+	// PodGroupTimedOut means the waiting members gave up before minAvailable was reached.
+	PodGroupTimedOut PodGroupPhase = "TimedOut"
+)
+
+// PodGroupPlacement is how the members of a pod group are placed across the
+// topology domains of the cluster.
+type PodGroupPlacement string
+
+const (
+	// PodGroupPlacementPack favours the topology domains already holding
+	// members of the pod group, for members talking to each other heavily.
+	PodGroupPlacementPack PodGroupPlacement = "Pack"
+	// PodGroupPlacementSpread favours the topology domains holding the fewest
+	// members of the pod group, for fault tolerant services.
+	PodGroupPlacementSpread PodGroupPlacement = "Spread"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PodGroup is a collection of pods that should be scheduled together.
+type PodGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PodGroupSpec   `json:"spec,omitempty"`
+	Status PodGroupStatus `json:"status,omitempty"`
+}
+
+// PodGroupSpec represents the template of a pod group.
+type PodGroupSpec struct {
+	// MinAvailable is the minimum number of members that must be scheduled
+	// together before any of them is allowed to bind.
+	MinAvailable int32 `json:"minAvailable"`
+
+	// MaxMember is the maximum number of members scheduled at the same time.
+	// Members beyond MinAvailable are scheduled as they fit, without waiting
+	// for each other, and members beyond MaxMember stay pending. There is no
+	// maximum when it is not set.
+	// +optional
+	MaxMember *int32 `json:"maxMember,omitempty"`
+
+	// MinRoles are the minimum numbers of members of each role, keyed by the
+	// role label of the members, that must be scheduled together on top of
+	// MinAvailable, e.g. ps: 1 and worker: 4 for a parameter server job.
+	// Their sum must not exceed MinAvailable. Members of other roles, or
+	// without a role, only count towards MinAvailable.
+	// +optional
+	MinRoles map[string]int32 `json:"minRoles,omitempty"`
+
+	// ScheduleTimeoutSeconds is how long a member waits in Permit for its
+	// siblings. The scheduler default is used when it is not set.
+	// +optional
+	ScheduleTimeoutSeconds *int32 `json:"scheduleTimeoutSeconds,omitempty"`
+
+	// Placement is how the members are placed across the topology domains
+	// of the cluster, such as zones or racks: Pack or Spread. Defaults to
+	// Pack.
+	// +optional
+	Placement PodGroupPlacement `json:"placement,omitempty"`
+
+	// RequiredTopologyKeys are node labels, from the widest topology domain
+	// to the narrowest, e.g. the zone then the rack. The members must all be
+	// placed in a single domain: the narrowest that can hold MinAvailable
+	// members, within a domain of the widest key at least. The domain is
+	// kept as long as the group has members.
+	// +optional
+	RequiredTopologyKeys []string `json:"requiredTopologyKeys,omitempty"`
+
+	// PriorityClassName is the priority class of the whole group. It takes
+	// precedence over the priorities of the members when the queue is sorted.
+	// When it is not set, the highest priority of the members is used.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+}
+
+// PodGroupStatus represents the current state of a pod group.
+type PodGroupStatus struct {
+	// Phase is the current phase of the pod group.
+	// +optional
+	Phase PodGroupPhase `json:"phase,omitempty"`
+
+	// Running is the number of running members.
+	// +optional
+	Running int32 `json:"running,omitempty"`
+
+	// Waiting is the number of members held in Permit.
+	// +optional
+	Waiting int32 `json:"waiting,omitempty"`
+
+	// Bound is the number of members bound to a node, running or not.
+	// +optional
+	Bound int32 `json:"bound,omitempty"`
+
+	// Succeeded is the number of members that finished successfully.
+	// +optional
+	Succeeded int32 `json:"succeeded,omitempty"`
+
+	// Failed is the number of failed members.
+	// +optional
+	Failed int32 `json:"failed,omitempty"`
+
+	// Roles are the member counts of each role the spec sets a minimum for.
+	// +optional
+	Roles map[string]PodGroupRoleStatus `json:"roles,omitempty"`
+
+	// LastTransitionTime is the last time the phase changed.
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// PodGroupRoleStatus represents the current state of the members of a role.
+type PodGroupRoleStatus struct {
+	// Waiting is the number of members of the role held in Permit.
+	// +optional
+	Waiting int32 `json:"waiting,omitempty"`
+
+	// Bound is the number of members of the role bound to a node, running or
+	// not.
+	// +optional
+	Bound int32 `json:"bound,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PodGroupList is a collection of pod groups.
+type PodGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []PodGroup `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Queue is the share of the cluster resources of a team. A pod is in the
+// queue named by its queue label, or else in the queue named like its
+// namespace. Pods in no Queue are not limited.
+type Queue struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec QueueSpec `json:"spec,omitempty"`
+}
+
+// QueueSpec represents the resources of a queue.
+type QueueSpec struct {
+	// Min are the resources guaranteed to the queue. Other queues may
+	// borrow them while they are idle, their gangs are preempted when a gang
+	// of the queue needs them back. They must not exceed Max.
+	// +optional
+	Min v1.ResourceList `json:"min,omitempty"`
+
+	// Max caps the resources requested by the pods of the queue holding a
+	// node. A gang is only admitted if all its members fit under it.
+	// Resources Max does not name are not capped.
+	// +optional
+	Max v1.ResourceList `json:"max,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// QueueList is a collection of queues.
+type QueueList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Queue `json:"items"`
+}
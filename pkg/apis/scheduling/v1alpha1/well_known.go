@@ -0,0 +1,84 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The pod labels the scheduler reads the groups and roles of pods from,
+// unless it is configured with others.
+const (
+	// PodGroupLabel names the group of a pod.
+	PodGroupLabel = "pod-group.scheduling.bdap.com/podgroup-configmap"
+	// RoleLabel names the role of a member.
+	RoleLabel = "pod-group.scheduling.bdap.com/role"
+)
+
+// The keys of the legacy ConfigMaps declaring groups, named after the fields
+// of PodGroupSpec they stand for. MinRolesKey holds role=count pairs
+// separated by commas, RequiredTopologyKeysKey label keys separated by commas.
+const (
+	MinAvailableKey           = "minAvailable"
+	MaxMemberKey              = "maxMember"
+	MinRolesKey               = "minRoles"
+	PlacementKey              = "placement"
+	RequiredTopologyKeysKey   = "requiredTopologyKeys"
+	ScheduleTimeoutSecondsKey = "scheduleTimeoutSeconds"
+	PriorityClassNameKey      = "priorityClassName"
+)
+
+// ParseMinAvailable parses the minAvailable of a ConfigMap. Whether it is
+// positive is for the caller to check.
+func ParseMinAvailable(str string) (int32, error) {
+	minAvailable, err := strconv.ParseInt(str, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an integer", str)
+	}
+	return int32(minAvailable), nil
+}
+
+// ParseMinRoles parses the minRoles of a ConfigMap, role=count pairs
+// separated by commas such as "ps=1,worker=4". Whether the roles and counts
+// are valid is for the caller to check.
+func ParseMinRoles(str string) (map[string]int32, error) {
+	minRoles := map[string]int32{}
+	for _, pair := range strings.Split(str, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%q is not role=count", pair)
+		}
+		role := strings.TrimSpace(kv[0])
+		count, err := strconv.ParseInt(strings.TrimSpace(kv[1]), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("count of role %q is not an integer: %q", role, kv[1])
+		}
+		minRoles[role] = int32(count)
+	}
+	return minRoles, nil
+}
+
+// Annotations used to report the status of groups declared by ConfigMaps.
+// RolesAnnotation holds the per-role counts as the JSON of
+// PodGroupStatus.Roles.
+const (
+	PhaseAnnotation              = "status.scheduling.bdap.com/phase"
+	RunningAnnotation            = "status.scheduling.bdap.com/running"
+	WaitingAnnotation            = "status.scheduling.bdap.com/waiting"
+	BoundAnnotation              = "status.scheduling.bdap.com/bound"
+	SucceededAnnotation          = "status.scheduling.bdap.com/succeeded"
+	FailedAnnotation             = "status.scheduling.bdap.com/failed"
+	RolesAnnotation              = "status.scheduling.bdap.com/roles"
+	LastTransitionTimeAnnotation = "status.scheduling.bdap.com/last-transition-time"
+)
+
+// BorrowedAnnotation is set by the scheduler on the pods admitted beyond the
+// min of their Queue, alone or with their gang. It holds the resources
+// borrowed, separated by commas. It tells why the pods were let in, the pods
+// preempted when another Queue takes its min back are chosen by what their
+// Queue uses beyond its min at that time.
+const BorrowedAnnotation = "scheduling.bdap.com/borrowed"
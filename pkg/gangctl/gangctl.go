@@ -0,0 +1,116 @@
+// Package gangctl implements gangctl, the command line tool managing the
+// groups of the gang scheduler and inspecting their members.
+package gangctl
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+	pgclientset "github.com/FFFFFaraway/gang-scheduler/pkg/generated/clientset/versioned"
+)
+
+// clients are the clients a command talks to the cluster with.
+type clients struct {
+	kube kubernetes.Interface
+	pg   pgclientset.Interface
+	// namespace is the namespace of the kubeconfig context, unless the
+	// namespace flag is set.
+	namespace string
+}
+
+// connectFunc connects to the cluster selected by the flags in o.
+type connectFunc func(o *options) (*clients, error)
+
+// options are the flags shared by every command.
+type options struct {
+	kubeconfig string
+	context    string
+	namespace  string
+	// labelKey is the pod label naming the group of a pod, the
+	// groupLabelKey of the scheduler.
+	labelKey string
+	// roleKey is the pod label naming the role of a member, the
+	// roleLabelKey of the scheduler.
+	roleKey string
+
+	connect connectFunc
+}
+
+// NewCommand returns the gangctl command. Installed as kubectl-gang in the
+// PATH, it is also run by "kubectl gang".
+func NewCommand() *cobra.Command {
+	return newCommand(connect)
+}
+
+func newCommand(connect connectFunc) *cobra.Command {
+	o := &options{connect: connect}
+	cmd := &cobra.Command{
+		Use:   "gangctl",
+		Short: "Manage the groups of the gang scheduler and inspect their members",
+		Long: `gangctl creates, updates and deletes the groups of the gang scheduler,
+attaches the group label to the pod template of workloads, and lists and
+describes groups with the state of their members.`,
+		SilenceUsage: true,
+	}
+	flags := cmd.PersistentFlags()
+	flags.StringVar(&o.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file, the kubectl one by default")
+	flags.StringVar(&o.context, "context", "", "The kubeconfig context to use")
+	flags.StringVarP(&o.namespace, "namespace", "n", "", "The namespace of the groups, the one of the kubeconfig context by default")
+	flags.StringVar(&o.labelKey, "group-label", v1alpha1.PodGroupLabel, "The pod label naming the group of a pod, the groupLabelKey of the scheduler")
+	flags.StringVar(&o.roleKey, "role-label", v1alpha1.RoleLabel, "The pod label naming the role of a member, the roleLabelKey of the scheduler")
+
+	cmd.AddCommand(
+		newCreateCommand(o),
+		newUpdateCommand(o),
+		newDeleteCommand(o),
+		newLabelCommand(o),
+		newListCommand(o),
+		newDescribeCommand(o),
+	)
+	return cmd
+}
+
+// connect connects to the cluster of the kubeconfig, the way kubectl does.
+func connect(o *options) (*clients, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = o.kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules,
+		&clientcmd.ConfigOverrides{CurrentContext: o.context})
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, err
+	}
+	kube, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	pg, err := pgclientset.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &clients{kube: kube, pg: pg, namespace: namespace}, nil
+}
+
+// clients connects to the cluster and returns the namespace to work in.
+func (o *options) clients() (*clients, string, error) {
+	c, err := o.connect(o)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to connect to the cluster: %v", err)
+	}
+	namespace := o.namespace
+	if namespace == "" {
+		namespace = c.namespace
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	return c, namespace, nil
+}
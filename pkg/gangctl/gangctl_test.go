@@ -0,0 +1,33 @@
+package gangctl
+
+import (
+	"bytes"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	pgfake "github.com/FFFFFaraway/gang-scheduler/pkg/generated/clientset/versioned/fake"
+)
+
+// fakeClients returns clients of fake clientsets holding objects, the
+// PodGroups in the PodGroup one and the rest in the kubernetes one.
+func fakeClients(kubeObjects, pgObjects []runtime.Object) *clients {
+	return &clients{
+		kube:      kubefake.NewSimpleClientset(kubeObjects...),
+		pg:        pgfake.NewSimpleClientset(pgObjects...),
+		namespace: "ns",
+	}
+}
+
+// run runs gangctl with args against c, and returns its output.
+func run(t *testing.T, c *clients, args ...string) (string, error) {
+	t.Helper()
+	cmd := newCommand(func(*options) (*clients, error) { return c, nil })
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
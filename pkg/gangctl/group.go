@@ -0,0 +1,437 @@
+package gangctl
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+)
+
+// The kinds of objects declaring groups.
+const (
+	sourcePodGroup  = "PodGroup"
+	sourceConfigMap = "ConfigMap"
+)
+
+// group is a group as declared by a PodGroup or a legacy ConfigMap.
+type group struct {
+	namespace string
+	name      string
+	source    string
+	// minAvailable, maxMember, minRoles, placement, requiredTopologyKeys,
+	// scheduleTimeoutSeconds and priorityClassName are as declared, a
+	// ConfigMap may hold values the scheduler rejects. minRoles are role=count
+	// pairs separated by commas, requiredTopologyKeys keys separated by commas.
+	minAvailable           string
+	maxMember              string
+	minRoles               string
+	placement              string
+	requiredTopologyKeys   string
+	scheduleTimeoutSeconds string
+	priorityClassName      string
+	// phase is the phase reported by the scheduler, if any.
+	phase        v1alpha1.PodGroupPhase
+	creationTime time.Time
+}
+
+func fromPodGroup(pg *v1alpha1.PodGroup) group {
+	g := group{
+		namespace:            pg.Namespace,
+		name:                 pg.Name,
+		source:               sourcePodGroup,
+		minAvailable:         strconv.Itoa(int(pg.Spec.MinAvailable)),
+		minRoles:             formatMinRoles(pg.Spec.MinRoles),
+		placement:            string(pg.Spec.Placement),
+		requiredTopologyKeys: strings.Join(pg.Spec.RequiredTopologyKeys, ","),
+		priorityClassName:    pg.Spec.PriorityClassName,
+		phase:                pg.Status.Phase,
+		creationTime:         pg.CreationTimestamp.Time,
+	}
+	if pg.Spec.MaxMember != nil {
+		g.maxMember = strconv.Itoa(int(*pg.Spec.MaxMember))
+	}
+	if pg.Spec.ScheduleTimeoutSeconds != nil {
+		g.scheduleTimeoutSeconds = strconv.Itoa(int(*pg.Spec.ScheduleTimeoutSeconds))
+	}
+	return g
+}
+
+// fromConfigMap returns the group declared by cm, false if cm declares none.
+func fromConfigMap(cm *v1.ConfigMap) (group, bool) {
+	ma, exist := cm.Data[v1alpha1.MinAvailableKey]
+	if !exist {
+		return group{}, false
+	}
+	return group{
+		namespace:              cm.Namespace,
+		name:                   cm.Name,
+		source:                 sourceConfigMap,
+		minAvailable:           ma,
+		maxMember:              cm.Data[v1alpha1.MaxMemberKey],
+		minRoles:               cm.Data[v1alpha1.MinRolesKey],
+		placement:              cm.Data[v1alpha1.PlacementKey],
+		requiredTopologyKeys:   cm.Data[v1alpha1.RequiredTopologyKeysKey],
+		scheduleTimeoutSeconds: cm.Data[v1alpha1.ScheduleTimeoutSecondsKey],
+		priorityClassName:      cm.Data[v1alpha1.PriorityClassNameKey],
+		phase:                  v1alpha1.PodGroupPhase(cm.Annotations[v1alpha1.PhaseAnnotation]),
+		creationTime:           cm.CreationTimestamp.Time,
+	}, true
+}
+
+// formatMinRoles formats minRoles the way a ConfigMap declares them, sorted
+// by role.
+func formatMinRoles(minRoles map[string]int32) string {
+	roles := make([]string, 0, len(minRoles))
+	for role := range minRoles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	pairs := make([]string, 0, len(roles))
+	for _, role := range roles {
+		pairs = append(pairs, fmt.Sprintf("%v=%d", role, minRoles[role]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// getGroup returns the group namespace/name the way the scheduler looks it
+// up: a PodGroup wins over a ConfigMap of the same name.
+func getGroup(ctx context.Context, c *clients, namespace, name string) (*group, error) {
+	pg, err := c.pg.SchedulingV1alpha1().PodGroups(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		g := fromPodGroup(pg)
+		return &g, nil
+	}
+	// A missing CRD is reported as not found too.
+	if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	cm, err := c.kube.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		if g, ok := fromConfigMap(cm); ok {
+			return &g, nil
+		}
+	}
+	return nil, fmt.Errorf("group %v/%v not found", namespace, name)
+}
+
+// listGroups returns the groups of namespace, of every namespace if it is
+// empty. A ConfigMap is left out when a PodGroup of the same name shadows it.
+func listGroups(ctx context.Context, c *clients, namespace string) ([]group, error) {
+	var groups []group
+	declared := map[string]bool{}
+	pgs, err := c.pg.SchedulingV1alpha1().PodGroups(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		for i := range pgs.Items {
+			groups = append(groups, fromPodGroup(&pgs.Items[i]))
+			declared[pgs.Items[i].Namespace+"/"+pgs.Items[i].Name] = true
+		}
+	}
+	cms, err := c.kube.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range cms.Items {
+		if g, ok := fromConfigMap(&cms.Items[i]); ok && !declared[g.namespace+"/"+g.name] {
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+// groupFlags are the flags setting the spec of a group.
+type groupFlags struct {
+	minAvailable           int32
+	maxMember              int32
+	minRoles               string
+	placement              string
+	requiredTopologyKeys   []string
+	scheduleTimeoutSeconds int32
+	priorityClassName      string
+
+	// roles are the parsed minRoles.
+	roles map[string]int32
+}
+
+func (f *groupFlags) add(cmd *cobra.Command) {
+	cmd.Flags().Int32Var(&f.minAvailable, "min-available", 0, "The minimum number of members scheduled together")
+	cmd.Flags().Int32Var(&f.maxMember, "max-member", 0, "The maximum number of members scheduled at the same time, 0 for no maximum")
+	cmd.Flags().StringVar(&f.minRoles, "min-roles", "", "The minimum number of members of each role scheduled together, e.g. ps=1,worker=4, empty for none")
+	cmd.Flags().StringVar(&f.placement, "placement", "", "How the members are placed across the topology domains: Pack, or Spread, empty for Pack")
+	cmd.Flags().StringSliceVar(&f.requiredTopologyKeys, "required-topology-keys", nil, "The node labels of the topology domains the members must all be placed in one of, from the widest to the narrowest, e.g. topology.kubernetes.io/zone,example.com/rack, empty for none")
+	cmd.Flags().Int32Var(&f.scheduleTimeoutSeconds, "schedule-timeout-seconds", 0, "How long a member waits for its siblings, 0 for the scheduler default")
+	cmd.Flags().StringVar(&f.priorityClassName, "priority-class-name", "", "The priority class of the whole group, empty for none")
+}
+
+func (f *groupFlags) validate(cmd *cobra.Command) error {
+	if cmd.Flags().Changed("min-available") && f.minAvailable < 1 {
+		return fmt.Errorf("--min-available must be positive, got %v", f.minAvailable)
+	}
+	if f.maxMember < 0 {
+		return fmt.Errorf("--max-member must not be negative, got %v", f.maxMember)
+	}
+	roles, err := v1alpha1.ParseMinRoles(f.minRoles)
+	if err != nil {
+		return fmt.Errorf("--min-roles must be role=count pairs: %v", err)
+	}
+	for role, min := range roles {
+		if role == "" || min < 1 {
+			return fmt.Errorf("--min-roles must give every role a positive count, got %v=%d", role, min)
+		}
+	}
+	f.roles = roles
+	switch v1alpha1.PodGroupPlacement(f.placement) {
+	case "", v1alpha1.PodGroupPlacementPack, v1alpha1.PodGroupPlacementSpread:
+	default:
+		return fmt.Errorf("--placement must be %v or %v, got %q", v1alpha1.PodGroupPlacementPack, v1alpha1.PodGroupPlacementSpread, f.placement)
+	}
+	for _, key := range f.requiredTopologyKeys {
+		if msgs := validation.IsQualifiedName(key); len(msgs) > 0 {
+			return fmt.Errorf("--required-topology-keys must be label keys, got %q: %v", key, strings.Join(msgs, ", "))
+		}
+	}
+	if f.scheduleTimeoutSeconds < 0 {
+		return fmt.Errorf("--schedule-timeout-seconds must not be negative, got %v", f.scheduleTimeoutSeconds)
+	}
+	return nil
+}
+
+// applyToPodGroup sets the flags changed on the command line in spec.
+func (f *groupFlags) applyToPodGroup(cmd *cobra.Command, spec *v1alpha1.PodGroupSpec) {
+	if cmd.Flags().Changed("min-available") {
+		spec.MinAvailable = f.minAvailable
+	}
+	if cmd.Flags().Changed("max-member") {
+		spec.MaxMember = nil
+		if f.maxMember > 0 {
+			mm := f.maxMember
+			spec.MaxMember = &mm
+		}
+	}
+	if cmd.Flags().Changed("min-roles") {
+		spec.MinRoles = nil
+		if len(f.roles) > 0 {
+			spec.MinRoles = f.roles
+		}
+	}
+	if cmd.Flags().Changed("placement") {
+		spec.Placement = v1alpha1.PodGroupPlacement(f.placement)
+	}
+	if cmd.Flags().Changed("required-topology-keys") {
+		spec.RequiredTopologyKeys = nil
+		if len(f.requiredTopologyKeys) > 0 {
+			spec.RequiredTopologyKeys = f.requiredTopologyKeys
+		}
+	}
+	if cmd.Flags().Changed("schedule-timeout-seconds") {
+		spec.ScheduleTimeoutSeconds = nil
+		if f.scheduleTimeoutSeconds > 0 {
+			sts := f.scheduleTimeoutSeconds
+			spec.ScheduleTimeoutSeconds = &sts
+		}
+	}
+	if cmd.Flags().Changed("priority-class-name") {
+		spec.PriorityClassName = f.priorityClassName
+	}
+}
+
+// applyToConfigMap sets the flags changed on the command line in data.
+func (f *groupFlags) applyToConfigMap(cmd *cobra.Command, data map[string]string) {
+	if cmd.Flags().Changed("min-available") {
+		data[v1alpha1.MinAvailableKey] = strconv.Itoa(int(f.minAvailable))
+	}
+	if cmd.Flags().Changed("max-member") {
+		delete(data, v1alpha1.MaxMemberKey)
+		if f.maxMember > 0 {
+			data[v1alpha1.MaxMemberKey] = strconv.Itoa(int(f.maxMember))
+		}
+	}
+	if cmd.Flags().Changed("min-roles") {
+		delete(data, v1alpha1.MinRolesKey)
+		if len(f.roles) > 0 {
+			data[v1alpha1.MinRolesKey] = formatMinRoles(f.roles)
+		}
+	}
+	if cmd.Flags().Changed("placement") {
+		delete(data, v1alpha1.PlacementKey)
+		if f.placement != "" {
+			data[v1alpha1.PlacementKey] = f.placement
+		}
+	}
+	if cmd.Flags().Changed("required-topology-keys") {
+		delete(data, v1alpha1.RequiredTopologyKeysKey)
+		if len(f.requiredTopologyKeys) > 0 {
+			data[v1alpha1.RequiredTopologyKeysKey] = strings.Join(f.requiredTopologyKeys, ",")
+		}
+	}
+	if cmd.Flags().Changed("schedule-timeout-seconds") {
+		delete(data, v1alpha1.ScheduleTimeoutSecondsKey)
+		if f.scheduleTimeoutSeconds > 0 {
+			data[v1alpha1.ScheduleTimeoutSecondsKey] = strconv.Itoa(int(f.scheduleTimeoutSeconds))
+		}
+	}
+	if cmd.Flags().Changed("priority-class-name") {
+		delete(data, v1alpha1.PriorityClassNameKey)
+		if f.priorityClassName != "" {
+			data[v1alpha1.PriorityClassNameKey] = f.priorityClassName
+		}
+	}
+}
+
+func newCreateCommand(o *options) *cobra.Command {
+	var f groupFlags
+	var source string
+	cmd := &cobra.Command{
+		Use:   "create NAME --min-available N",
+		Short: "Create a group",
+		Example: `  # Create a group of at least 3 members waiting 5 minutes for each other
+  gangctl create job1 --min-available 3 --schedule-timeout-seconds 300`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("min-available") {
+				return fmt.Errorf("--min-available is required")
+			}
+			if source != sourcePodGroup && source != sourceConfigMap {
+				return fmt.Errorf("--source must be %v or %v, got %q", sourcePodGroup, sourceConfigMap, source)
+			}
+			if err := f.validate(cmd); err != nil {
+				return err
+			}
+			c, namespace, err := o.clients()
+			if err != nil {
+				return err
+			}
+			ctx := context.TODO()
+			name := args[0]
+			if g, err := getGroup(ctx, c, namespace, name); err == nil {
+				return fmt.Errorf("group %v/%v already exists as a %v", namespace, name, g.source)
+			}
+			meta := metav1.ObjectMeta{Namespace: namespace, Name: name}
+			switch source {
+			case sourcePodGroup:
+				pg := &v1alpha1.PodGroup{ObjectMeta: meta}
+				f.applyToPodGroup(cmd, &pg.Spec)
+				_, err = c.pg.SchedulingV1alpha1().PodGroups(namespace).Create(ctx, pg, metav1.CreateOptions{})
+			case sourceConfigMap:
+				cm := &v1.ConfigMap{ObjectMeta: meta, Data: map[string]string{}}
+				f.applyToConfigMap(cmd, cm.Data)
+				_, err = c.kube.CoreV1().ConfigMaps(namespace).Create(ctx, cm, metav1.CreateOptions{})
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "group %v/%v created as a %v\n", namespace, name, source)
+			return nil
+		},
+	}
+	f.add(cmd)
+	cmd.Flags().StringVar(&source, "source", sourcePodGroup, "The kind of object declaring the group: PodGroup, or ConfigMap for clusters without the PodGroup CRD")
+	return cmd
+}
+
+func newUpdateCommand(o *options) *cobra.Command {
+	var f groupFlags
+	cmd := &cobra.Command{
+		Use:   "update NAME",
+		Short: "Update a group, leaving what no flag sets unchanged",
+		Example: `  # Let the members of job1 wait for each other with the scheduler default timeout
+  gangctl update job1 --schedule-timeout-seconds 0`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := f.validate(cmd); err != nil {
+				return err
+			}
+			c, namespace, err := o.clients()
+			if err != nil {
+				return err
+			}
+			ctx := context.TODO()
+			name := args[0]
+			g, err := getGroup(ctx, c, namespace, name)
+			if err != nil {
+				return err
+			}
+			switch g.source {
+			case sourcePodGroup:
+				var pg *v1alpha1.PodGroup
+				pg, err = c.pg.SchedulingV1alpha1().PodGroups(namespace).Get(ctx, name, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				f.applyToPodGroup(cmd, &pg.Spec)
+				_, err = c.pg.SchedulingV1alpha1().PodGroups(namespace).Update(ctx, pg, metav1.UpdateOptions{})
+			case sourceConfigMap:
+				var cm *v1.ConfigMap
+				cm, err = c.kube.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				f.applyToConfigMap(cmd, cm.Data)
+				_, err = c.kube.CoreV1().ConfigMaps(namespace).Update(ctx, cm, metav1.UpdateOptions{})
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "group %v/%v updated\n", namespace, name)
+			return nil
+		},
+	}
+	f.add(cmd)
+	return cmd
+}
+
+func newDeleteCommand(o *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a group",
+		Long: `Delete a group. Both the PodGroup and the ConfigMap declaring it are
+deleted, so that the ConfigMap does not take over once the PodGroup is gone.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, namespace, err := o.clients()
+			if err != nil {
+				return err
+			}
+			ctx := context.TODO()
+			name := args[0]
+			err = c.pg.SchedulingV1alpha1().PodGroups(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			deleted := err == nil
+			cm, err := c.kube.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			if err == nil {
+				if _, ok := fromConfigMap(cm); ok {
+					err = c.kube.CoreV1().ConfigMaps(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+					if err != nil && !apierrors.IsNotFound(err) {
+						return err
+					}
+					deleted = deleted || err == nil
+				}
+			}
+			if !deleted {
+				return fmt.Errorf("group %v/%v not found", namespace, name)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "group %v/%v deleted\n", namespace, name)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,215 @@
+package gangctl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+)
+
+func newPodGroup(name string, minAvailable int32) *v1alpha1.PodGroup {
+	return &v1alpha1.PodGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+		Spec:       v1alpha1.PodGroupSpec{MinAvailable: minAvailable},
+	}
+}
+
+func newGroupConfigMap(name string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"}, Data: data}
+}
+
+func TestGroupCommands(t *testing.T) {
+	tests := []struct {
+		name        string
+		kubeObjects []runtime.Object
+		pgObjects   []runtime.Object
+		args        []string
+		expectErr   bool
+		// expectedPodGroup and expectedConfigMap are the objects named pg
+		// after the command, nil if there is none.
+		expectedPodGroup  *v1alpha1.PodGroupSpec
+		expectedConfigMap map[string]string
+	}{
+		{
+			name: "create a PodGroup",
+			args: []string{"create", "pg", "--min-available", "3", "--max-member", "5",
+				"--schedule-timeout-seconds", "60", "--priority-class-name", "high"},
+			expectedPodGroup: &v1alpha1.PodGroupSpec{MinAvailable: 3, MaxMember: pointer.Int32Ptr(5),
+				ScheduleTimeoutSeconds: pointer.Int32Ptr(60), PriorityClassName: "high"},
+		},
+		{
+			name:              "create a ConfigMap",
+			args:              []string{"create", "pg", "--min-available", "3", "--max-member", "5", "--source", "ConfigMap"},
+			expectedConfigMap: map[string]string{v1alpha1.MinAvailableKey: "3", v1alpha1.MaxMemberKey: "5"},
+		},
+		{
+			name:             "create a PodGroup with roles",
+			args:             []string{"create", "pg", "--min-available", "5", "--min-roles", "ps=1,worker=4"},
+			expectedPodGroup: &v1alpha1.PodGroupSpec{MinAvailable: 5, MinRoles: map[string]int32{"ps": 1, "worker": 4}},
+		},
+		{
+			name:              "create a ConfigMap with roles",
+			args:              []string{"create", "pg", "--min-available", "5", "--min-roles", "worker=4,ps=1", "--source", "ConfigMap"},
+			expectedConfigMap: map[string]string{v1alpha1.MinAvailableKey: "5", v1alpha1.MinRolesKey: "ps=1,worker=4"},
+		},
+		{
+			name:      "roles need members",
+			args:      []string{"create", "pg", "--min-available", "5", "--min-roles", "ps=0"},
+			expectErr: true,
+		},
+		{
+			name:             "create a spreading PodGroup",
+			args:             []string{"create", "pg", "--min-available", "3", "--placement", "Spread"},
+			expectedPodGroup: &v1alpha1.PodGroupSpec{MinAvailable: 3, Placement: v1alpha1.PodGroupPlacementSpread},
+		},
+		{
+			name:              "create a spreading ConfigMap",
+			args:              []string{"create", "pg", "--min-available", "3", "--placement", "Spread", "--source", "ConfigMap"},
+			expectedConfigMap: map[string]string{v1alpha1.MinAvailableKey: "3", v1alpha1.PlacementKey: "Spread"},
+		},
+		{
+			name:             "create a PodGroup requiring a topology domain",
+			args:             []string{"create", "pg", "--min-available", "3", "--required-topology-keys", "topology.kubernetes.io/zone,example.com/rack"},
+			expectedPodGroup: &v1alpha1.PodGroupSpec{MinAvailable: 3, RequiredTopologyKeys: []string{"topology.kubernetes.io/zone", "example.com/rack"}},
+		},
+		{
+			name: "clear the required topology of a ConfigMap",
+			kubeObjects: []runtime.Object{newGroupConfigMap("pg", map[string]string{
+				v1alpha1.MinAvailableKey: "3", v1alpha1.RequiredTopologyKeysKey: "example.com/rack"})},
+			args:              []string{"update", "pg", "--required-topology-keys", ""},
+			expectedConfigMap: map[string]string{v1alpha1.MinAvailableKey: "3"},
+		},
+		{
+			name:      "invalid topology key",
+			args:      []string{"create", "pg", "--min-available", "3", "--required-topology-keys", "a rack"},
+			expectErr: true,
+		},
+		{
+			name:      "unknown placement",
+			args:      []string{"create", "pg", "--min-available", "3", "--placement", "Scatter"},
+			expectErr: true,
+		},
+		{
+			name:      "minAvailable is required",
+			args:      []string{"create", "pg"},
+			expectErr: true,
+		},
+		{
+			name:      "minAvailable must be positive",
+			args:      []string{"create", "pg", "--min-available", "0"},
+			expectErr: true,
+		},
+		{
+			name:      "unknown source",
+			args:      []string{"create", "pg", "--min-available", "3", "--source", "Secret"},
+			expectErr: true,
+		},
+		{
+			name:              "the group exists as a ConfigMap",
+			kubeObjects:       []runtime.Object{newGroupConfigMap("pg", map[string]string{v1alpha1.MinAvailableKey: "2"})},
+			args:              []string{"create", "pg", "--min-available", "3"},
+			expectErr:         true,
+			expectedConfigMap: map[string]string{v1alpha1.MinAvailableKey: "2"},
+		},
+		{
+			name:              "a ConfigMap declaring no group does not conflict",
+			kubeObjects:       []runtime.Object{newGroupConfigMap("pg", map[string]string{"other": "2"})},
+			args:              []string{"create", "pg", "--min-available", "3"},
+			expectedPodGroup:  &v1alpha1.PodGroupSpec{MinAvailable: 3},
+			expectedConfigMap: map[string]string{"other": "2"},
+		},
+		{
+			name:             "update a PodGroup",
+			pgObjects:        []runtime.Object{newPodGroup("pg", 3)},
+			args:             []string{"update", "pg", "--schedule-timeout-seconds", "60"},
+			expectedPodGroup: &v1alpha1.PodGroupSpec{MinAvailable: 3, ScheduleTimeoutSeconds: pointer.Int32Ptr(60)},
+		},
+		{
+			name: "update a ConfigMap",
+			kubeObjects: []runtime.Object{newGroupConfigMap("pg", map[string]string{
+				v1alpha1.MinAvailableKey: "3", v1alpha1.ScheduleTimeoutSecondsKey: "60", v1alpha1.PriorityClassNameKey: "high"})},
+			args:              []string{"update", "pg", "--min-available", "4", "--schedule-timeout-seconds", "0", "--priority-class-name", ""},
+			expectedConfigMap: map[string]string{v1alpha1.MinAvailableKey: "4"},
+		},
+		{
+			name:              "update the PodGroup shadowing a ConfigMap",
+			kubeObjects:       []runtime.Object{newGroupConfigMap("pg", map[string]string{v1alpha1.MinAvailableKey: "2"})},
+			pgObjects:         []runtime.Object{newPodGroup("pg", 3)},
+			args:              []string{"update", "pg", "--min-available", "4"},
+			expectedPodGroup:  &v1alpha1.PodGroupSpec{MinAvailable: 4},
+			expectedConfigMap: map[string]string{v1alpha1.MinAvailableKey: "2"},
+		},
+		{
+			name:             "clear the roles of a PodGroup",
+			pgObjects:        []runtime.Object{&v1alpha1.PodGroup{ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "ns"}, Spec: v1alpha1.PodGroupSpec{MinAvailable: 5, MinRoles: map[string]int32{"ps": 1}}}},
+			args:             []string{"update", "pg", "--min-roles", ""},
+			expectedPodGroup: &v1alpha1.PodGroupSpec{MinAvailable: 5},
+		},
+		{
+			name:      "update a missing group",
+			args:      []string{"update", "pg", "--min-available", "4"},
+			expectErr: true,
+		},
+		{
+			name:        "delete both declarations",
+			kubeObjects: []runtime.Object{newGroupConfigMap("pg", map[string]string{v1alpha1.MinAvailableKey: "2"})},
+			pgObjects:   []runtime.Object{newPodGroup("pg", 3)},
+			args:        []string{"delete", "pg"},
+		},
+		{
+			name:              "delete leaves a ConfigMap declaring no group",
+			kubeObjects:       []runtime.Object{newGroupConfigMap("pg", map[string]string{"other": "2"})},
+			args:              []string{"delete", "pg"},
+			expectErr:         true,
+			expectedConfigMap: map[string]string{"other": "2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fakeClients(tt.kubeObjects, tt.pgObjects)
+			out, err := run(t, c, tt.args...)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("expected error %v, got %v: %v", tt.expectErr, err, out)
+			}
+
+			pg, err := c.pg.SchedulingV1alpha1().PodGroups("ns").Get(context.TODO(), "pg", metav1.GetOptions{})
+			switch {
+			case apierrors.IsNotFound(err):
+				if tt.expectedPodGroup != nil {
+					t.Errorf("expected the PodGroup %+v, got none", tt.expectedPodGroup)
+				}
+			case err != nil:
+				t.Fatalf("fail to get the PodGroup: %v", err)
+			case tt.expectedPodGroup == nil:
+				t.Errorf("expected no PodGroup, got %+v", pg.Spec)
+			default:
+				if diff := cmp.Diff(*tt.expectedPodGroup, pg.Spec); diff != "" {
+					t.Errorf("unexpected PodGroup spec (-want, +got): %s", diff)
+				}
+			}
+
+			cm, err := c.kube.CoreV1().ConfigMaps("ns").Get(context.TODO(), "pg", metav1.GetOptions{})
+			switch {
+			case apierrors.IsNotFound(err):
+				if tt.expectedConfigMap != nil {
+					t.Errorf("expected the ConfigMap %v, got none", tt.expectedConfigMap)
+				}
+			case err != nil:
+				t.Fatalf("fail to get the ConfigMap: %v", err)
+			case tt.expectedConfigMap == nil:
+				t.Errorf("expected no ConfigMap, got %v", cm.Data)
+			default:
+				if diff := cmp.Diff(tt.expectedConfigMap, cm.Data); diff != "" {
+					t.Errorf("unexpected ConfigMap data (-want, +got): %s", diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package gangctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// patchFunc patches the workload namespace/name.
+type patchFunc func(ctx context.Context, c *clients, namespace, name string, patch []byte) error
+
+// workloads are the kinds of workloads whose pod template can be labeled, by
+// the names kubectl knows them by. The pod template of a Job can not be
+// changed once it is created.
+var workloads = map[string]patchFunc{}
+
+func init() {
+	patchDeployment := func(ctx context.Context, c *clients, namespace, name string, patch []byte) error {
+		_, err := c.kube.AppsV1().Deployments(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	}
+	patchStatefulSet := func(ctx context.Context, c *clients, namespace, name string, patch []byte) error {
+		_, err := c.kube.AppsV1().StatefulSets(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	}
+	patchReplicaSet := func(ctx context.Context, c *clients, namespace, name string, patch []byte) error {
+		_, err := c.kube.AppsV1().ReplicaSets(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	}
+	for _, kind := range []string{"deployment", "deployments", "deploy"} {
+		workloads[kind] = patchDeployment
+	}
+	for _, kind := range []string{"statefulset", "statefulsets", "sts"} {
+		workloads[kind] = patchStatefulSet
+	}
+	for _, kind := range []string{"replicaset", "replicasets", "rs"} {
+		workloads[kind] = patchReplicaSet
+	}
+}
+
+func newLabelCommand(o *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "label KIND/NAME GROUP",
+		Short: "Attach the group label to the pod template of a workload",
+		Long: `Attach the group label to the pod template of a deployment, statefulset
+or replicaset, so that the pods it creates are members of the group. The pod
+template of a job can not be changed once it is created, so a job must carry
+the label in its manifest.`,
+		Example: `  # Make the pods of the deployment trainer members of the group job1
+  gangctl label deployment/trainer job1`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kind, name := splitWorkload(args[0])
+			patchWorkload, exist := workloads[strings.ToLower(kind)]
+			if !exist || name == "" {
+				return fmt.Errorf("expected KIND/NAME of a deployment, statefulset or replicaset, got %q", args[0])
+			}
+			group := args[1]
+			c, namespace, err := o.clients()
+			if err != nil {
+				return err
+			}
+			ctx := context.TODO()
+			if _, err := getGroup(ctx, c, namespace, group); err != nil {
+				// The members wait until the group is created.
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+			}
+			patch, err := json.Marshal(map[string]interface{}{
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"metadata": map[string]interface{}{
+							"labels": map[string]string{o.labelKey: group},
+						},
+					},
+				},
+			})
+			if err != nil {
+				return err
+			}
+			if err := patchWorkload(ctx, c, namespace, name, patch); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%v/%v labeled with group %v\n", kind, name, group)
+			return nil
+		},
+	}
+}
+
+// splitWorkload splits KIND/NAME.
+func splitWorkload(arg string) (kind, name string) {
+	if i := strings.Index(arg, "/"); i >= 0 {
+		return arg[:i], arg[i+1:]
+	}
+	return arg, ""
+}
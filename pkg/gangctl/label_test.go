@@ -0,0 +1,48 @@
+package gangctl
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+)
+
+func TestLabel(t *testing.T) {
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "trainer", Namespace: "ns"}}
+	c := fakeClients([]runtime.Object{deployment}, []runtime.Object{newPodGroup("pg", 2)})
+
+	if _, err := run(t, c, "label", "deploy/trainer", "pg"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := c.kube.AppsV1().Deployments("ns").Get(context.TODO(), "trainer", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("fail to get the deployment: %v", err)
+	}
+	if group := got.Spec.Template.Labels[v1alpha1.PodGroupLabel]; group != "pg" {
+		t.Errorf("expected the pod template labeled with pg, got %v", got.Spec.Template.Labels)
+	}
+
+	out, err := run(t, c, "label", "deploy/trainer", "missing", "--group-label", "group")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "warning: group ns/missing not found") {
+		t.Errorf("expected a warning about the missing group, got %q", out)
+	}
+	got, _ = c.kube.AppsV1().Deployments("ns").Get(context.TODO(), "trainer", metav1.GetOptions{})
+	if group := got.Spec.Template.Labels["group"]; group != "missing" {
+		t.Errorf("expected the pod template labeled with missing, got %v", got.Spec.Template.Labels)
+	}
+
+	// The pod template of a job is immutable.
+	for _, args := range [][]string{{"label", "pod/trainer", "pg"}, {"label", "job/trainer", "pg"}, {"label", "deploy", "pg"}, {"label", "deploy/missing", "pg"}} {
+		if _, err := run(t, c, args...); err == nil {
+			t.Errorf("expected %q to fail", args)
+		}
+	}
+}
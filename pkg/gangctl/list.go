@@ -0,0 +1,230 @@
+package gangctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+)
+
+// memberCounts are the counts of the members of a group as the apiserver
+// knows them, terminating members left out.
+type memberCounts struct {
+	total, pending, bound, running, succeeded, failed int
+}
+
+func countMembers(pods []*v1.Pod) memberCounts {
+	var c memberCounts
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		c.total++
+		switch {
+		case pod.Status.Phase == v1.PodSucceeded:
+			c.succeeded++
+		case pod.Status.Phase == v1.PodFailed:
+			c.failed++
+		case pod.Spec.NodeName == "":
+			c.pending++
+		default:
+			c.bound++
+			if pod.Status.Phase == v1.PodRunning {
+				c.running++
+			}
+		}
+	}
+	return c
+}
+
+// phase returns the phase the scheduler reported for g, or the one computed
+// from its members when none is reported yet. Only the scheduler knows the
+// members waiting for their siblings.
+func (c memberCounts) phase(g *group) v1alpha1.PodGroupPhase {
+	if g.phase != "" {
+		return g.phase
+	}
+	ma, err := v1alpha1.ParseMinAvailable(g.minAvailable)
+	if err != nil {
+		return "Unknown"
+	}
+	minAvailable := int(ma)
+	switch {
+	case c.failed > 0 && c.total-c.failed < minAvailable:
+		return v1alpha1.PodGroupFailed
+	case c.running+c.succeeded >= minAvailable:
+		return v1alpha1.PodGroupRunning
+	case c.bound+c.succeeded >= minAvailable:
+		return v1alpha1.PodGroupScheduled
+	}
+	return v1alpha1.PodGroupPending
+}
+
+// listMembers returns the members of the groups of namespace, of every
+// namespace if it is empty, by group key.
+func (o *options) listMembers(ctx context.Context, c *clients, namespace string, selector string) (map[string][]*v1.Pod, error) {
+	pods, err := c.kube.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	members := map[string][]*v1.Pod{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if name := pod.Labels[o.labelKey]; name != "" {
+			key := pod.Namespace + "/" + name
+			members[key] = append(members[key], pod)
+		}
+	}
+	return members, nil
+}
+
+func newListCommand(o *options) *cobra.Command {
+	var allNamespaces bool
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the groups with the state of their members",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, namespace, err := o.clients()
+			if err != nil {
+				return err
+			}
+			if allNamespaces {
+				namespace = ""
+			}
+			ctx := context.TODO()
+			groups, err := listGroups(ctx, c, namespace)
+			if err != nil {
+				return err
+			}
+			members, err := o.listMembers(ctx, c, namespace, o.labelKey)
+			if err != nil {
+				return err
+			}
+			sort.Slice(groups, func(i, j int) bool {
+				if groups[i].namespace != groups[j].namespace {
+					return groups[i].namespace < groups[j].namespace
+				}
+				return groups[i].name < groups[j].name
+			})
+			printGroups(cmd.OutOrStdout(), groups, members, allNamespaces, time.Now())
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "List the groups of every namespace")
+	return cmd
+}
+
+func printGroups(out io.Writer, groups []group, members map[string][]*v1.Pod, allNamespaces bool, now time.Time) {
+	w := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+	defer w.Flush()
+	if allNamespaces {
+		fmt.Fprint(w, "NAMESPACE\t")
+	}
+	fmt.Fprintln(w, "NAME\tSOURCE\tMIN-AVAILABLE\tMEMBERS\tPENDING\tBOUND\tRUNNING\tSUCCEEDED\tFAILED\tPHASE\tAGE")
+	for i := range groups {
+		g := &groups[i]
+		c := countMembers(members[g.namespace+"/"+g.name])
+		if allNamespaces {
+			fmt.Fprintf(w, "%v\t", g.namespace)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", g.name, g.source, g.minAvailable,
+			c.total, c.pending, c.bound, c.running, c.succeeded, c.failed, c.phase(g), age(g.creationTime, now))
+	}
+}
+
+func newDescribeCommand(o *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "describe NAME",
+		Short: "Show a group with its members, their phases and their nodes",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, namespace, err := o.clients()
+			if err != nil {
+				return err
+			}
+			ctx := context.TODO()
+			g, err := getGroup(ctx, c, namespace, args[0])
+			if err != nil {
+				return err
+			}
+			members, err := o.listMembers(ctx, c, namespace, o.labelKey+"="+g.name)
+			if err != nil {
+				return err
+			}
+			pods := members[g.namespace+"/"+g.name]
+			sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+			describeGroup(cmd.OutOrStdout(), g, pods, o.roleKey, time.Now())
+			return nil
+		},
+	}
+}
+
+// describeGroup writes g and its members, with their role when g sets
+// per-role minimums, the role of a member being its roleKey label.
+func describeGroup(out io.Writer, g *group, pods []*v1.Pod, roleKey string, now time.Time) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	defer w.Flush()
+	c := countMembers(pods)
+	fmt.Fprintf(w, "Name:\t%v\n", g.name)
+	fmt.Fprintf(w, "Namespace:\t%v\n", g.namespace)
+	fmt.Fprintf(w, "Source:\t%v\n", g.source)
+	fmt.Fprintf(w, "MinAvailable:\t%v\n", g.minAvailable)
+	fmt.Fprintf(w, "MaxMember:\t%v\n", orNone(g.maxMember, "<none>"))
+	fmt.Fprintf(w, "MinRoles:\t%v\n", orNone(g.minRoles, "<none>"))
+	fmt.Fprintf(w, "Placement:\t%v\n", orNone(g.placement, string(v1alpha1.PodGroupPlacementPack)))
+	fmt.Fprintf(w, "RequiredTopologyKeys:\t%v\n", orNone(g.requiredTopologyKeys, "<none>"))
+	fmt.Fprintf(w, "ScheduleTimeoutSeconds:\t%v\n", orNone(g.scheduleTimeoutSeconds, "<scheduler default>"))
+	fmt.Fprintf(w, "PriorityClassName:\t%v\n", orNone(g.priorityClassName, "<none>"))
+	fmt.Fprintf(w, "Phase:\t%v\n", c.phase(g))
+	fmt.Fprintf(w, "Members:\t%v total, %v pending, %v bound, %v running, %v succeeded, %v failed\n",
+		c.total, c.pending, c.bound, c.running, c.succeeded, c.failed)
+	if len(pods) == 0 {
+		fmt.Fprintf(w, "Pods:\t<none>\n")
+		return
+	}
+	fmt.Fprintf(w, "Pods:\n")
+	withRoles := g.minRoles != ""
+	if withRoles {
+		fmt.Fprintf(w, "  NAME\tROLE\tPHASE\tNODE\tAGE\n")
+	} else {
+		fmt.Fprintf(w, "  NAME\tPHASE\tNODE\tAGE\n")
+	}
+	for _, pod := range pods {
+		phase := string(pod.Status.Phase)
+		if pod.DeletionTimestamp != nil {
+			phase = "Terminating"
+		}
+		fmt.Fprintf(w, "  %v\t", pod.Name)
+		if withRoles {
+			fmt.Fprintf(w, "%v\t", orNone(pod.Labels[roleKey], "<none>"))
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\n", orNone(phase, "Unknown"), orNone(pod.Spec.NodeName, "<none>"),
+			age(pod.CreationTimestamp.Time, now))
+	}
+}
+
+func orNone(s, none string) string {
+	if s == "" {
+		return none
+	}
+	return s
+}
+
+// age returns how long ago t was, the way kubectl prints it.
+func age(t, now time.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(now.Sub(t))
+}
@@ -0,0 +1,206 @@
+package gangctl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+)
+
+func member(name, group, nodeName string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Labels: map[string]string{v1alpha1.PodGroupLabel: group}},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestCountMembers(t *testing.T) {
+	deleting := member("pod6", "pg", "node1", corev1.PodRunning)
+	deleting.DeletionTimestamp = &metav1.Time{}
+	pods := []*corev1.Pod{
+		member("pod1", "pg", "", corev1.PodPending),
+		member("pod2", "pg", "node1", corev1.PodPending),
+		member("pod3", "pg", "node1", corev1.PodRunning),
+		member("pod4", "pg", "node1", corev1.PodSucceeded),
+		member("pod5", "pg", "node1", corev1.PodFailed),
+		deleting,
+	}
+	expected := memberCounts{total: 5, pending: 1, bound: 2, running: 1, succeeded: 1, failed: 1}
+	if got := countMembers(pods); got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestPhase(t *testing.T) {
+	tests := []struct {
+		name     string
+		group    group
+		counts   memberCounts
+		expected v1alpha1.PodGroupPhase
+	}{
+		{
+			name:     "reported phase",
+			group:    group{minAvailable: "2", phase: v1alpha1.PodGroupWaiting},
+			counts:   memberCounts{total: 2, pending: 2},
+			expected: v1alpha1.PodGroupWaiting,
+		},
+		{
+			name:     "pending",
+			group:    group{minAvailable: "2"},
+			counts:   memberCounts{total: 2, pending: 1, bound: 1},
+			expected: v1alpha1.PodGroupPending,
+		},
+		{
+			name:     "scheduled",
+			group:    group{minAvailable: "2"},
+			counts:   memberCounts{total: 2, bound: 2, running: 1},
+			expected: v1alpha1.PodGroupScheduled,
+		},
+		{
+			name:     "running",
+			group:    group{minAvailable: "2"},
+			counts:   memberCounts{total: 2, bound: 1, running: 1, succeeded: 1},
+			expected: v1alpha1.PodGroupRunning,
+		},
+		{
+			name:     "failed",
+			group:    group{minAvailable: "2"},
+			counts:   memberCounts{total: 2, bound: 1, running: 1, failed: 1},
+			expected: v1alpha1.PodGroupFailed,
+		},
+		{
+			name:     "invalid minAvailable",
+			group:    group{minAvailable: "two"},
+			expected: "Unknown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.counts.phase(&tt.group); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	shadowed := newGroupConfigMap("pg1", map[string]string{v1alpha1.MinAvailableKey: "5"})
+	reported := newGroupConfigMap("pg2", map[string]string{v1alpha1.MinAvailableKey: "1"})
+	reported.Annotations = map[string]string{v1alpha1.PhaseAnnotation: string(v1alpha1.PodGroupTimedOut)}
+	other := newPodGroup("pg3", 1)
+	other.Namespace = "other"
+	c := fakeClients([]runtime.Object{
+		shadowed, reported,
+		newGroupConfigMap("not-a-group", map[string]string{"key": "value"}),
+		member("pod1", "pg1", "node1", corev1.PodRunning),
+		member("pod2", "pg1", "node2", corev1.PodRunning),
+		member("pod3", "pg2", "", corev1.PodPending),
+	}, []runtime.Object{newPodGroup("pg1", 2), other})
+
+	out, err := run(t, c, "list")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := [][]string{
+		{"NAME", "SOURCE", "MIN-AVAILABLE", "MEMBERS", "PENDING", "BOUND", "RUNNING", "SUCCEEDED", "FAILED", "PHASE", "AGE"},
+		{"pg1", "PodGroup", "2", "2", "0", "2", "2", "0", "0", "Running", "<unknown>"},
+		{"pg2", "ConfigMap", "1", "1", "1", "0", "0", "0", "0", "TimedOut", "<unknown>"},
+	}
+	if diff := cmp.Diff(expected, fields(out)); diff != "" {
+		t.Errorf("unexpected output (-want, +got): %s", diff)
+	}
+
+	out, err = run(t, c, "list", "-A")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := len(fields(out)); got != 4 {
+		t.Errorf("expected the groups of every namespace, got %q", out)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	c := fakeClients([]runtime.Object{
+		member("pod2", "pg", "", corev1.PodPending),
+		member("pod1", "pg", "node1", corev1.PodRunning),
+		member("pod3", "other", "node1", corev1.PodRunning),
+	}, []runtime.Object{newPodGroup("pg", 2)})
+
+	out, err := run(t, c, "describe", "pg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := [][]string{
+		{"Name:", "pg"},
+		{"Namespace:", "ns"},
+		{"Source:", "PodGroup"},
+		{"MinAvailable:", "2"},
+		{"MaxMember:", "<none>"},
+		{"MinRoles:", "<none>"},
+		{"Placement:", "Pack"},
+		{"RequiredTopologyKeys:", "<none>"},
+		{"ScheduleTimeoutSeconds:", "<scheduler", "default>"},
+		{"PriorityClassName:", "<none>"},
+		{"Phase:", "Pending"},
+		{"Members:", "2", "total,", "1", "pending,", "1", "bound,", "1", "running,", "0", "succeeded,", "0", "failed"},
+		{"Pods:"},
+		{"NAME", "PHASE", "NODE", "AGE"},
+		{"pod1", "Running", "node1", "<unknown>"},
+		{"pod2", "Pending", "<none>", "<unknown>"},
+	}
+	if diff := cmp.Diff(expected, fields(out)); diff != "" {
+		t.Errorf("unexpected output (-want, +got): %s", diff)
+	}
+
+	if _, err := run(t, c, "describe", "missing"); err == nil {
+		t.Errorf("expected an error describing a missing group")
+	}
+}
+
+// fields splits the lines of out into their fields.
+func fields(out string) [][]string {
+	var lines [][]string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		lines = append(lines, strings.Fields(line))
+	}
+	return lines
+}
+
+func TestDescribeRoles(t *testing.T) {
+	g := &group{name: "pg", namespace: "ns", source: sourceConfigMap, minAvailable: "2", minRoles: "ps=1,worker=1"}
+	ps := member("ps-0", "pg", "node1", corev1.PodRunning)
+	ps.Labels[v1alpha1.RoleLabel] = "ps"
+	var out bytes.Buffer
+	describeGroup(&out, g, []*corev1.Pod{ps, member("other", "pg", "", corev1.PodPending)}, v1alpha1.RoleLabel, time.Now())
+	lines := fields(out.String())
+	expected := [][]string{
+		{"MinRoles:", "ps=1,worker=1"},
+		{"Pods:"},
+		{"NAME", "ROLE", "PHASE", "NODE", "AGE"},
+		{"ps-0", "ps", "Running", "node1", "<unknown>"},
+		{"other", "<none>", "Pending", "<none>", "<unknown>"},
+	}
+	if diff := cmp.Diff(expected, append(lines[5:6], lines[len(lines)-4:]...)); diff != "" {
+		t.Errorf("unexpected output (-want, +got): %s", diff)
+	}
+}
+
+func TestDescribeAge(t *testing.T) {
+	now := time.Now()
+	g := &group{name: "pg", namespace: "ns", source: sourcePodGroup, minAvailable: "1"}
+	pod := member("pod1", "pg", "node1", corev1.PodRunning)
+	pod.CreationTimestamp = metav1.NewTime(now.Add(-90 * time.Second))
+	var out bytes.Buffer
+	describeGroup(&out, g, []*corev1.Pod{pod}, v1alpha1.RoleLabel, now)
+	if !strings.Contains(out.String(), "90s") {
+		t.Errorf("expected the age of the member, got %q", out.String())
+	}
+}
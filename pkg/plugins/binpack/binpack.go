@@ -0,0 +1,116 @@
+package binpack
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config"
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config/v1beta1"
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config/validation"
+	"github.com/FFFFFaraway/gang-scheduler/pkg/plugins/resources"
+)
+
+// Name is plugin name
+const Name = "binpack"
+
+var _ framework.ScorePlugin = &Binpack{}
+
+// Binpack packs the pods asking for extended resources, such as GPUs, onto
+// the nodes already using them, so that whole nodes are left free for the
+// pods, or gangs of pods, asking for a lot of them.
+type Binpack struct {
+	handle framework.Handle
+	args   *config.BinpackArgs
+}
+
+func New(obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+	args, err := getArgs(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &Binpack{handle: handle, args: args}, nil
+}
+
+// getArgs returns the validated args of the plugin. The scheduler passes none
+// when the args are not registered in its scheme, the defaults are used then.
+func getArgs(obj runtime.Object) (*config.BinpackArgs, error) {
+	if obj == nil {
+		return defaultArgs(), nil
+	}
+	args, ok := obj.(*config.BinpackArgs)
+	if !ok {
+		return nil, fmt.Errorf("want args to be of type BinpackArgs, got %T", obj)
+	}
+	if err := validation.ValidateBinpackArgs(args); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+func defaultArgs() *config.BinpackArgs {
+	var v1beta1Args v1beta1.BinpackArgs
+	v1beta1.SetDefaults_BinpackArgs(&v1beta1Args)
+	var args config.BinpackArgs
+	// The conversion of these types never fails.
+	_ = v1beta1.Convert_v1beta1_BinpackArgs_To_config_BinpackArgs(&v1beta1Args, &args, nil)
+	return &args
+}
+
+func (b *Binpack) Name() string {
+	return Name
+}
+
+// Score averages the scores of the node for every configured resource the
+// pod asks for, see resourceScore. Pods asking for none score 0 everywhere.
+func (b *Binpack) Score(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (int64, *framework.Status) {
+	ni, err := b.handle.SnapshotSharedLister().NodeInfos().Get(nodeName)
+	if err != nil {
+		return 0, framework.NewStatus(framework.Error, fmt.Sprintf("getting node %q from snapshot: %v", nodeName, err))
+	}
+	requests := resources.PodRequest(pod).ScalarResources
+	var sum, n int64
+	for _, name := range b.args.Resources {
+		rn := v1.ResourceName(name)
+		if requests[rn] <= 0 {
+			continue
+		}
+		sum += resourceScore(ni.Allocatable.ScalarResources[rn], ni.Requested.ScalarResources[rn], requests[rn])
+		n++
+	}
+	if n == 0 {
+		return 0, framework.NewStatus(framework.Success, "")
+	}
+	return sum / n, framework.NewStatus(framework.Success, "")
+}
+
+// resourceScore scores a node with allocatable of a resource, requested of
+// which are already taken, for a pod asking for request more:
+//   - a node left full scores MaxNodeScore, there is nothing left to break up;
+//   - a partly used node scores above half of MaxNodeScore, the more so the
+//     fuller it is left, so that the pod goes to the best fit;
+//   - a free node scores below half of MaxNodeScore, the less so the bigger
+//     it is, so that whole nodes are only broken up as a last resort and the
+//     smallest first.
+//
+// A node the pod does not fit in scores 0, Filter rejects it anyway.
+func resourceScore(allocatable, requested, request int64) int64 {
+	if allocatable <= 0 || requested+request > allocatable {
+		return 0
+	}
+	if requested+request == allocatable {
+		return framework.MaxNodeScore
+	}
+	half := framework.MaxNodeScore / 2
+	if requested > 0 {
+		return half + half*(requested+request)/allocatable
+	}
+	return half * request / allocatable
+}
+
+func (b *Binpack) ScoreExtensions() framework.ScoreExtensions {
+	return nil
+}
@@ -0,0 +1,152 @@
+package binpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config"
+)
+
+const gpu corev1.ResourceName = "nvidia.com/gpu"
+
+type fakeHandle struct {
+	framework.Handle
+	nodeInfos []*framework.NodeInfo
+}
+
+func (h *fakeHandle) SnapshotSharedLister() framework.SharedLister {
+	return &fakeSharedLister{nodeInfos: h.nodeInfos}
+}
+
+type fakeSharedLister struct {
+	nodeInfos []*framework.NodeInfo
+}
+
+func (l *fakeSharedLister) NodeInfos() framework.NodeInfoLister {
+	return l
+}
+
+func (l *fakeSharedLister) List() ([]*framework.NodeInfo, error) {
+	return l.nodeInfos, nil
+}
+
+func (l *fakeSharedLister) HavePodsWithAffinityList() ([]*framework.NodeInfo, error) {
+	return nil, nil
+}
+
+func (l *fakeSharedLister) HavePodsWithRequiredAntiAffinityList() ([]*framework.NodeInfo, error) {
+	return nil, nil
+}
+
+func (l *fakeSharedLister) Get(nodeName string) (*framework.NodeInfo, error) {
+	for _, ni := range l.nodeInfos {
+		if ni.Node() != nil && ni.Node().Name == nodeName {
+			return ni, nil
+		}
+	}
+	return nil, errors.NewNotFound(corev1.Resource("node"), nodeName)
+}
+
+func gpuPod(name string, gpus string) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: corev1.PodSpec{Containers: []corev1.Container{{}}}}
+	if gpus != "" {
+		pod.Spec.Containers[0].Resources.Requests = corev1.ResourceList{gpu: resource.MustParse(gpus)}
+	}
+	return pod
+}
+
+// gpuNode returns the NodeInfo of a node with gpus, used by as many single
+// gpu pods.
+func gpuNode(name, gpus string, used int) *framework.NodeInfo {
+	var pods []*corev1.Pod
+	for i := 0; i < used; i++ {
+		pods = append(pods, gpuPod(name, "1"))
+	}
+	ni := framework.NewNodeInfo(pods...)
+	_ = ni.SetNode(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("32"),
+			gpu:                resource.MustParse(gpus),
+		}},
+	})
+	return ni
+}
+
+func TestScore(t *testing.T) {
+	nodeInfos := []*framework.NodeInfo{
+		gpuNode("free", "8", 0),
+		gpuNode("half", "8", 4),
+		gpuNode("almost-full", "8", 7),
+		gpuNode("small-free", "2", 0),
+		gpuNode("cpu-only", "0", 0),
+	}
+	b := &Binpack{handle: &fakeHandle{nodeInfos: nodeInfos}, args: defaultArgs()}
+	for _, tt := range []struct {
+		name     string
+		pod      *corev1.Pod
+		expected map[string]int64
+	}{
+		{
+			name: "single gpu pod fills the fullest node and breaks no free one",
+			pod:  gpuPod("pod", "1"),
+			expected: map[string]int64{
+				"free": 6, "half": 81, "almost-full": 100, "small-free": 25, "cpu-only": 0,
+			},
+		},
+		{
+			name: "whole node pod takes a free node",
+			pod:  gpuPod("pod", "8"),
+			expected: map[string]int64{
+				"free": 100, "half": 0, "almost-full": 0, "small-free": 0, "cpu-only": 0,
+			},
+		},
+		{
+			name: "pod without gpus",
+			pod:  gpuPod("pod", ""),
+			expected: map[string]int64{
+				"free": 0, "half": 0, "almost-full": 0, "small-free": 0, "cpu-only": 0,
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]int64{}
+			for _, ni := range nodeInfos {
+				score, status := b.Score(context.TODO(), framework.NewCycleState(), tt.pod, ni.Node().Name)
+				if !status.IsSuccess() {
+					t.Fatalf("unexpected status %v", status)
+				}
+				got[ni.Node().Name] = score
+			}
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("unexpected scores (-want,+got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestScoreAveragesResources(t *testing.T) {
+	const fpga corev1.ResourceName = "example.com/fpga"
+	ni := gpuNode("node", "4", 3)
+	ni.Allocatable.SetScalar(fpga, 4)
+	b := &Binpack{handle: &fakeHandle{nodeInfos: []*framework.NodeInfo{ni}},
+		args: &config.BinpackArgs{Resources: []string{string(gpu), string(fpga)}}}
+	pod := gpuPod("pod", "1")
+	pod.Spec.Containers[0].Resources.Requests[fpga] = resource.MustParse("2")
+
+	score, status := b.Score(context.TODO(), framework.NewCycleState(), pod, "node")
+	if !status.IsSuccess() {
+		t.Fatalf("unexpected status %v", status)
+	}
+	// The gpus are left full, 100, and half the free fpgas are taken, 25.
+	if score != 62 {
+		t.Errorf("expected 62, got %v", score)
+	}
+}
@@ -0,0 +1,23 @@
+// Package resources holds what the plugins share about the resources of pods.
+package resources
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// PodRequest returns the resources a pod needs on a node: the bigger of the
+// sum of its containers and any single init container, plus its overhead.
+func PodRequest(pod *v1.Pod) *framework.Resource {
+	res := &framework.Resource{}
+	for _, c := range pod.Spec.Containers {
+		res.Add(c.Resources.Requests)
+	}
+	for _, c := range pod.Spec.InitContainers {
+		res.SetMaxResource(c.Resources.Requests)
+	}
+	if pod.Spec.Overhead != nil {
+		res.Add(pod.Spec.Overhead)
+	}
+	return res
+}
@@ -0,0 +1,32 @@
+package resources
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestPodRequest(t *testing.T) {
+	const gpu v1.ResourceName = "nvidia.com/gpu"
+	container := func(cpu, gpus string) v1.Container {
+		return v1.Container{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+			v1.ResourceCPU: resource.MustParse(cpu),
+			gpu:            resource.MustParse(gpus),
+		}}}
+	}
+	pod := &v1.Pod{Spec: v1.PodSpec{
+		Containers: []v1.Container{container("1", "2"), container("1", "2")},
+		// The init container needs more cpu than the containers together,
+		// less gpus.
+		InitContainers: []v1.Container{container("3", "1")},
+		Overhead:       v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")},
+	}}
+	got := PodRequest(pod)
+	if got.MilliCPU != 3100 {
+		t.Errorf("expected 3100 millicpu, got %v", got.MilliCPU)
+	}
+	if got.ScalarResources[gpu] != 4 {
+		t.Errorf("expected 4 gpus, got %v", got.ScalarResources[gpu])
+	}
+}
@@ -0,0 +1,108 @@
+package sample
+
+import (
+	"sync"
+	"time"
+
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+// denialTTL is how long the members of a group rejected as a unit are denied
+// in PreFilter, so that they do not grab nodes one by one before the gang is
+// retried.
+const denialTTL = 3 * time.Second
+
+// newDenialCache returns the cache of the groups rejected as a unit, keyed by
+// namespace/group, with why they were as values.
+func newDenialCache() *utilcache.LRUExpireCache {
+	return utilcache.NewLRUExpireCache(1024)
+}
+
+// groupBackoff keeps the members of a group that failed to assemble out of
+// the cluster for an exponentially growing delay, so that an oversized gang
+// does not grab nodes over and over again. Groups are keyed by
+// namespace/name.
+type groupBackoff struct {
+	initial time.Duration
+	max     time.Duration
+	clock   clock.PassiveClock
+
+	lock    sync.Mutex
+	entries map[string]*backoffEntry
+}
+
+type backoffEntry struct {
+	failures int
+	until    time.Time
+}
+
+func newGroupBackoff(initial, max time.Duration) *groupBackoff {
+	return &groupBackoff{
+		initial: initial,
+		max:     max,
+		clock:   clock.RealClock{},
+		entries: map[string]*backoffEntry{},
+	}
+}
+
+// fail records a failed attempt of the group and returns the delay it backs
+// off for, and whether the failure started a new backoff. The members of one
+// attempt fail together, so failures reported while the group is already
+// backing off are ignored.
+func (b *groupBackoff) fail(key string) (time.Duration, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := b.clock.Now()
+	b.gc(now)
+	e, exist := b.entries[key]
+	if !exist {
+		e = &backoffEntry{}
+		b.entries[key] = e
+	}
+	if now.Before(e.until) {
+		return e.until.Sub(now), false
+	}
+	e.failures++
+	delay := b.initial
+	for i := 1; i < e.failures && delay < b.max; i++ {
+		delay *= 2
+	}
+	if delay > b.max {
+		delay = b.max
+	}
+	e.until = now.Add(delay)
+	return delay, true
+}
+
+// remaining returns how long the group still backs off, 0 if it does not.
+func (b *groupBackoff) remaining(key string) time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	e, exist := b.entries[key]
+	if !exist {
+		return 0
+	}
+	if d := e.until.Sub(b.clock.Now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// reset forgets the failures of the group once it is scheduled.
+func (b *groupBackoff) reset(key string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.entries, key)
+}
+
+// gc forgets the groups that have not failed for longer than the max delay,
+// their next failure starts from the initial delay again.
+func (b *groupBackoff) gc(now time.Time) {
+	for key, e := range b.entries {
+		if now.Sub(e.until) > b.max {
+			delete(b.entries, key)
+		}
+	}
+}
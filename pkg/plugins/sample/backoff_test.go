@@ -0,0 +1,55 @@
+package sample
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+func TestGroupBackoff(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	b := newGroupBackoff(time.Second, 4*time.Second)
+	b.clock = fakeClock
+
+	if d := b.remaining("ns/pg"); d != 0 {
+		t.Fatalf("expected no backoff before any failure, got %v", d)
+	}
+
+	for i, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		if d, started := b.fail("ns/pg"); d != expected || !started {
+			t.Errorf("failure %d: expected a new backoff of %v, got %v started %v", i+1, expected, d, started)
+		}
+		// Siblings failing in the same attempt do not grow the delay.
+		if d, started := b.fail("ns/pg"); d != expected || started {
+			t.Errorf("failure %d: expected sibling failure to keep %v, got %v started %v", i+1, expected, d, started)
+		}
+		if d := b.remaining("ns/pg"); d != expected {
+			t.Errorf("failure %d: expected remaining %v, got %v", i+1, expected, d)
+		}
+		if d := b.remaining("ns/other"); d != 0 {
+			t.Errorf("expected other groups not to back off, got %v", d)
+		}
+		fakeClock.Step(expected)
+		if d := b.remaining("ns/pg"); d != 0 {
+			t.Errorf("failure %d: expected backoff to be over, got %v", i+1, d)
+		}
+	}
+
+	b.fail("ns/pg")
+	b.reset("ns/pg")
+	if d := b.remaining("ns/pg"); d != 0 {
+		t.Errorf("expected no backoff after reset, got %v", d)
+	}
+	if d, _ := b.fail("ns/pg"); d != time.Second {
+		t.Errorf("expected reset to start from the initial delay, got %v", d)
+	}
+
+	// A group quiet for longer than the max delay starts over.
+	fakeClock.Step(time.Second + 5*time.Second)
+	b.fail("ns/other")
+	fakeClock.Step(time.Second)
+	if d, _ := b.fail("ns/pg"); d != time.Second {
+		t.Errorf("expected an old group to start from the initial delay, got %v", d)
+	}
+}
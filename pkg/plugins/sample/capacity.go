@@ -0,0 +1,252 @@
+package sample
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/plugins/resources"
+)
+
+// capacityCacheTTL is how long the simulation of a group is reused by its
+// siblings before it is run again on a newer snapshot.
+const capacityCacheTTL = 3 * time.Second
+
+// capacityState is the result of placing the remaining members of a group on
+// the snapshot.
+type capacityState struct {
+	fits bool
+	msg  string
+}
+
+// newCapacityCache returns the cache sharing the simulation between the
+// scheduling cycles of sibling members, keyed by namespace/group.
+func newCapacityCache() *cache.LRUExpireCache {
+	return cache.NewLRUExpireCache(1024)
+}
+
+// checkCapacity tells whether the members of pg that still need a node can all
+// be placed on the snapshot, in a single topology domain if pg requires one,
+// see placeInDomain. The result is reused by siblings for capacityCacheTTL,
+// their scheduling cycles do not share a cycle state.
+func (s *Sample) checkCapacity(pod *v1.Pod, pg *podGroup) (*capacityState, error) {
+	key := pg.namespace + "/" + pg.name
+	if cached, ok := s.capacityCache.Get(key); ok {
+		return cached.(*capacityState), nil
+	}
+
+	nodeInfos, err := s.handle.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		return nil, err
+	}
+	var result *capacityState
+	if len(pg.requiredTopologyKeys) > 0 {
+		result = s.placeInDomain(nodeInfos, pod, pg)
+	} else {
+		result = simulateGang(nodeInfos, pod, pg, s.groupMembers(pg.namespace, pg.name), s.countSucceeded(), s.args.RoleLabelKey)
+	}
+	s.capacityCache.Add(key, result, capacityCacheTTL)
+	return result, nil
+}
+
+// simulateGang places the members of pg that have no node yet on a copy of
+// the free resources of every node, biggest members first, and reports
+// whether minAvailable members, and the minimum of every role, can be reached.
+// Succeeded members are part of the quorum when countSucceeded is set. The
+// role of a member is its roleLabel label.
+func simulateGang(nodeInfos []*framework.NodeInfo, pod *v1.Pod, pg *podGroup, members []*v1.Pod, countSucceeded bool, roleLabel string) *capacityState {
+	// Members are identified by name, they all live in the namespace of the group.
+	memberNames := sets.NewString(pod.Name)
+	for _, m := range members {
+		memberNames.Insert(m.Name)
+	}
+
+	// Members already bound, assumed or waiting in Permit are part of the snapshot.
+	placed := sets.NewString()
+	free := make([]*nodeFree, 0, len(nodeInfos))
+	for _, ni := range nodeInfos {
+		if ni.Node() == nil {
+			continue
+		}
+		for _, pi := range ni.Pods {
+			if pi.Pod.Namespace == pg.namespace && memberNames.Has(pi.Pod.Name) {
+				placed.Insert(pi.Pod.Name)
+			}
+		}
+		if ni.Node().Spec.Unschedulable {
+			continue
+		}
+		free = append(free, newNodeFree(ni))
+	}
+	// So are the bound members the snapshot misses, e.g. on a node being
+	// removed, they are counted the way Permit counts them.
+	for _, m := range members {
+		if m.DeletionTimestamp != nil {
+			continue
+		}
+		switch m.Status.Phase {
+		case v1.PodSucceeded:
+			if countSucceeded {
+				placed.Insert(m.Name)
+			}
+		case v1.PodFailed:
+		default:
+			if m.Spec.NodeName != "" {
+				placed.Insert(m.Name)
+			}
+		}
+	}
+
+	needed := pg.minAvailable - placed.Len()
+	// The members each role still needs.
+	roleNeeded := make(map[string]int, len(pg.minRoles))
+	for role, min := range pg.minRoles {
+		roleNeeded[role] = min
+	}
+	rolesShort := false
+	seen := sets.NewString()
+	for _, m := range append([]*v1.Pod{pod}, members...) {
+		if placed.Has(m.Name) && !seen.Has(m.Name) {
+			roleNeeded[m.Labels[roleLabel]]--
+		}
+		seen.Insert(m.Name)
+	}
+	for role := range pg.minRoles {
+		if roleNeeded[role] > 0 {
+			rolesShort = true
+		}
+	}
+	if needed <= 0 && !rolesShort {
+		return &capacityState{fits: true}
+	}
+
+	var pending []*v1.Pod
+	seen = sets.NewString()
+	for _, m := range append([]*v1.Pod{pod}, members...) {
+		if seen.Has(m.Name) || placed.Has(m.Name) || m.Spec.NodeName != "" || m.DeletionTimestamp != nil ||
+			m.Status.Phase == v1.PodSucceeded || m.Status.Phase == v1.PodFailed {
+			continue
+		}
+		seen.Insert(m.Name)
+		pending = append(pending, m)
+	}
+
+	requests := make(map[string]*framework.Resource, len(pending))
+	for _, p := range pending {
+		requests[p.Name] = resources.PodRequest(p)
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return resourceLess(requests[pending[j].Name], requests[pending[i].Name])
+	})
+
+	fit := 0
+	tried := sets.NewString()
+	place := func(p *v1.Pod) bool {
+		tried.Insert(p.Name)
+		for _, n := range free {
+			if n.fits(requests[p.Name]) {
+				n.take(requests[p.Name])
+				fit++
+				return true
+			}
+		}
+		return false
+	}
+	// The members of the roles short of their minimum go first, so that the
+	// members of other roles do not take their room.
+	for _, p := range pending {
+		if role := p.Labels[roleLabel]; roleNeeded[role] > 0 && place(p) {
+			roleNeeded[role]--
+		}
+	}
+	var short []string
+	for role := range pg.minRoles {
+		if roleNeeded[role] > 0 {
+			short = append(short, role)
+		}
+	}
+	if len(short) > 0 {
+		sort.Strings(short)
+		return &capacityState{
+			msg: fmt.Sprintf("podGroup %v/%v needs more members of the roles %v to reach their minimums, but they do not fit in the cluster",
+				pg.namespace, pg.name, short),
+		}
+	}
+	for _, p := range pending {
+		if fit >= needed {
+			break
+		}
+		if !tried.Has(p.Name) {
+			place(p)
+		}
+	}
+	if fit >= needed {
+		return &capacityState{fits: true}
+	}
+	return &capacityState{
+		msg: fmt.Sprintf("podGroup %v/%v needs %d more members to reach minAvailable(%d), but only %d of them fit in the cluster",
+			pg.namespace, pg.name, needed, pg.minAvailable, fit),
+	}
+}
+
+// resourceLess orders requests by cpu, then memory, then extended resources.
+func resourceLess(a, b *framework.Resource) bool {
+	if a.MilliCPU != b.MilliCPU {
+		return a.MilliCPU < b.MilliCPU
+	}
+	if a.Memory != b.Memory {
+		return a.Memory < b.Memory
+	}
+	var sa, sb int64
+	for _, v := range a.ScalarResources {
+		sa += v
+	}
+	for _, v := range b.ScalarResources {
+		sb += v
+	}
+	return sa < sb
+}
+
+// nodeFree is what is left of a node during the simulation.
+type nodeFree struct {
+	res  *framework.Resource
+	pods int
+}
+
+func newNodeFree(ni *framework.NodeInfo) *nodeFree {
+	res := ni.Allocatable.Clone()
+	res.MilliCPU -= ni.Requested.MilliCPU
+	res.Memory -= ni.Requested.Memory
+	res.EphemeralStorage -= ni.Requested.EphemeralStorage
+	for name, q := range ni.Requested.ScalarResources {
+		res.SetScalar(name, res.ScalarResources[name]-q)
+	}
+	return &nodeFree{res: res, pods: ni.Allocatable.AllowedPodNumber - len(ni.Pods)}
+}
+
+func (n *nodeFree) fits(req *framework.Resource) bool {
+	if n.pods < 1 || req.MilliCPU > n.res.MilliCPU || req.Memory > n.res.Memory || req.EphemeralStorage > n.res.EphemeralStorage {
+		return false
+	}
+	for name, q := range req.ScalarResources {
+		if q > n.res.ScalarResources[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *nodeFree) take(req *framework.Resource) {
+	n.pods--
+	n.res.MilliCPU -= req.MilliCPU
+	n.res.Memory -= req.Memory
+	n.res.EphemeralStorage -= req.EphemeralStorage
+	for name, q := range req.ScalarResources {
+		n.res.SetScalar(name, n.res.ScalarResources[name]-q)
+	}
+}
@@ -0,0 +1,173 @@
+package sample
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const gpu corev1.ResourceName = "nvidia.com/gpu"
+
+func gangMember(name string, requests corev1.ResourceList) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Labels: map[string]string{PodGroupName: "pg"}},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{Requests: requests}}},
+		},
+	}
+}
+
+// withRole sets the role of pod.
+func withRole(pod *corev1.Pod, role string) *corev1.Pod {
+	pod.Labels[PodGroupRole] = role
+	return pod
+}
+
+func gpuNode(name string, gpus string, pods ...*corev1.Pod) *framework.NodeInfo {
+	return newNodeInfo(name, corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("8"),
+		corev1.ResourceMemory: resource.MustParse("32Gi"),
+		corev1.ResourcePods:   resource.MustParse("110"),
+		gpu:                   resource.MustParse(gpus),
+	}, pods...)
+}
+
+func TestSimulateGang(t *testing.T) {
+	oneGPU := corev1.ResourceList{gpu: resource.MustParse("1")}
+	var fiveGPUMembers []*corev1.Pod
+	for i := 0; i < 5; i++ {
+		fiveGPUMembers = append(fiveGPUMembers, gangMember(fmt.Sprintf("member-%d", i), oneGPU))
+	}
+	bound := gangMember("member-0", oneGPU)
+	bound.Spec.NodeName = "node1"
+	succeeded := gangMember("member-0", oneGPU)
+	succeeded.Spec.NodeName = "node1"
+	succeeded.Status.Phase = corev1.PodSucceeded
+
+	withInit := gangMember("init", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")})
+	withInit.Spec.InitContainers = []corev1.Container{{Resources: corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("6")},
+	}}}
+	withOverhead := gangMember("overhead", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("7")})
+	withOverhead.Spec.Overhead = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")}
+
+	for _, tt := range []struct {
+		name           string
+		nodeInfos      []*framework.NodeInfo
+		pod            *corev1.Pod
+		members        []*corev1.Pod
+		minAvailable   int
+		minRoles       map[string]int
+		countSucceeded bool
+		fits           bool
+	}{
+		{
+			name:         "5 gpu members fit on 8 free gpus",
+			nodeInfos:    []*framework.NodeInfo{gpuNode("node1", "4"), gpuNode("node2", "4")},
+			pod:          fiveGPUMembers[0],
+			members:      fiveGPUMembers,
+			minAvailable: 5,
+			fits:         true,
+		},
+		{
+			name:         "5 gpu members do not fit on 3 free gpus",
+			nodeInfos:    []*framework.NodeInfo{gpuNode("node1", "2"), gpuNode("node2", "1")},
+			pod:          fiveGPUMembers[0],
+			members:      fiveGPUMembers,
+			minAvailable: 5,
+		},
+		{
+			name:         "members already on the snapshot are not placed again",
+			nodeInfos:    []*framework.NodeInfo{gpuNode("node1", "2", bound), gpuNode("node2", "3")},
+			pod:          fiveGPUMembers[1],
+			members:      append([]*corev1.Pod{bound}, fiveGPUMembers[1:]...),
+			minAvailable: 5,
+			fits:         true,
+		},
+		{
+			name:         "bound members missing from the snapshot are not placed again",
+			nodeInfos:    []*framework.NodeInfo{gpuNode("node2", "4")},
+			pod:          fiveGPUMembers[1],
+			members:      append([]*corev1.Pod{bound}, fiveGPUMembers[1:]...),
+			minAvailable: 5,
+			fits:         true,
+		},
+		{
+			name:           "succeeded members count",
+			nodeInfos:      []*framework.NodeInfo{gpuNode("node2", "4")},
+			pod:            fiveGPUMembers[1],
+			members:        append([]*corev1.Pod{succeeded}, fiveGPUMembers[1:]...),
+			minAvailable:   5,
+			countSucceeded: true,
+			fits:           true,
+		},
+		{
+			name:         "succeeded members are ignored",
+			nodeInfos:    []*framework.NodeInfo{gpuNode("node2", "4")},
+			pod:          fiveGPUMembers[1],
+			members:      append([]*corev1.Pod{succeeded}, fiveGPUMembers[1:]...),
+			minAvailable: 5,
+		},
+		{
+			name:         "init containers count when bigger than containers",
+			nodeInfos:    []*framework.NodeInfo{gpuNode("node1", "0")},
+			pod:          withInit,
+			members:      []*corev1.Pod{withInit, gangMember("other", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("3")})},
+			minAvailable: 2,
+		},
+		{
+			name:         "pod overhead counts",
+			nodeInfos:    []*framework.NodeInfo{gpuNode("node1", "0"), gpuNode("node2", "0")},
+			pod:          withOverhead,
+			members:      []*corev1.Pod{withOverhead, gangMember("other", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")})},
+			minAvailable: 2,
+		},
+		{
+			name:      "the roles short of their minimum are placed first",
+			nodeInfos: []*framework.NodeInfo{gpuNode("node1", "4")},
+			pod:       withRole(gangMember("worker-0", oneGPU), "worker"),
+			members: []*corev1.Pod{
+				withRole(gangMember("worker-0", oneGPU), "worker"),
+				withRole(gangMember("worker-1", oneGPU), "worker"),
+				withRole(gangMember("worker-2", oneGPU), "worker"),
+				withRole(gangMember("ps-0", corev1.ResourceList{gpu: resource.MustParse("2")}), "ps"),
+			},
+			minAvailable: 3,
+			minRoles:     map[string]int{"ps": 1, "worker": 2},
+			fits:         true,
+		},
+		{
+			name:      "a role that does not fit",
+			nodeInfos: []*framework.NodeInfo{gpuNode("node1", "4")},
+			pod:       withRole(gangMember("worker-0", oneGPU), "worker"),
+			members: []*corev1.Pod{
+				withRole(gangMember("worker-0", oneGPU), "worker"),
+				withRole(gangMember("worker-1", oneGPU), "worker"),
+				withRole(gangMember("ps-0", corev1.ResourceList{gpu: resource.MustParse("5")}), "ps"),
+			},
+			minAvailable: 2,
+			minRoles:     map[string]int{"ps": 1},
+		},
+		{
+			name:         "bound members count for their role",
+			nodeInfos:    []*framework.NodeInfo{gpuNode("node1", "2", withRole(bound, "ps"))},
+			pod:          withRole(fiveGPUMembers[1], "worker"),
+			members:      []*corev1.Pod{bound, fiveGPUMembers[1]},
+			minAvailable: 2,
+			minRoles:     map[string]int{"ps": 1, "worker": 1},
+			fits:         true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			pg := &podGroup{namespace: "ns", name: "pg", minAvailable: tt.minAvailable, minRoles: tt.minRoles}
+			got := simulateGang(tt.nodeInfos, tt.pod, pg, tt.members, tt.countSucceeded, PodGroupRole)
+			if got.fits != tt.fits {
+				t.Errorf("expected fits %v, got %v: %v", tt.fits, got.fits, got.msg)
+			}
+		})
+	}
+}
@@ -12,45 +12,12 @@ import (
 
 var emptyArgs = make([]config.PluginConfig, 0)
 
-type fakePodLister struct {
-	called int
-}
-
-// List lists all Pods in the indexer.
-func (*fakePodLister) List(selector labels.Selector) (ret []*corev1.Pod, err error) {
-	//TODO implement me
-	panic("implement me")
-}
-
-// Pods returns an object that can list and get Pods.
-func (*fakePodLister) Pods(namespace string) v1.PodNamespaceLister {
-	return &fakePodNamespaceLister{}
-}
-
-type fakePodNamespaceLister struct {
-}
-
-func (*fakePodNamespaceLister) Get(name string) (*corev1.Pod, error) {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (*fakePodNamespaceLister) List(selector labels.Selector) (ret []*corev1.Pod, err error) {
-	// 假设目前集群中，Pod状态如下
-	// namespace1:
-	if selector.Matches(labels.Set{PodGroupName: "pg1"}) {
-		ret = append(ret, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pg1-1", Namespace: "namespace1"}})
-		return ret, nil
-	}
-
-	if selector.Matches(labels.Set{PodGroupName: "pg2"}) {
-		ret = append(ret, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pg2-1", Namespace: "namespace2"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}})
-		ret = append(ret, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pg2-2", Namespace: "namespace2"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}})
-		ret = append(ret, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pg2-3", Namespace: "namespace2"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}})
-		return ret, nil
-	}
-
-	return ret, nil
+// 假设目前集群中，Pod状态如下
+var fakePods = []*corev1.Pod{
+	{ObjectMeta: metav1.ObjectMeta{Name: "pg1-1", Labels: map[string]string{PodGroupName: "pg1"}}},
+	{ObjectMeta: metav1.ObjectMeta{Name: "pg2-1", Labels: map[string]string{PodGroupName: "pg2"}}, Spec: corev1.PodSpec{NodeName: "node1"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+	{ObjectMeta: metav1.ObjectMeta{Name: "pg2-2", Labels: map[string]string{PodGroupName: "pg2"}}, Spec: corev1.PodSpec{NodeName: "node1"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+	{ObjectMeta: metav1.ObjectMeta{Name: "pg2-3", Labels: map[string]string{PodGroupName: "pg2"}}, Spec: corev1.PodSpec{NodeName: "node1"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
 }
 
 type fakeConfigMapLister struct {
@@ -93,8 +60,45 @@ func (l *fakeConfigMapNamespaceLister) Get(name string) (*corev1.ConfigMap, erro
 }
 
 type fakeSharedLister struct {
+	nodeInfos []*framework.NodeInfo
+}
+
+func (l *fakeSharedLister) NodeInfos() framework.NodeInfoLister {
+	return &fakeNodeInfoLister{nodeInfos: l.nodeInfos}
+}
+
+type fakeNodeInfoLister struct {
+	nodeInfos []*framework.NodeInfo
+}
+
+func (l *fakeNodeInfoLister) List() ([]*framework.NodeInfo, error) {
+	return l.nodeInfos, nil
+}
+
+func (l *fakeNodeInfoLister) HavePodsWithAffinityList() ([]*framework.NodeInfo, error) {
+	return nil, nil
+}
+
+func (l *fakeNodeInfoLister) HavePodsWithRequiredAntiAffinityList() ([]*framework.NodeInfo, error) {
+	return nil, nil
+}
+
+func (l *fakeNodeInfoLister) Get(nodeName string) (*framework.NodeInfo, error) {
+	for _, ni := range l.nodeInfos {
+		if ni.Node() != nil && ni.Node().Name == nodeName {
+			return ni, nil
+		}
+	}
+	return nil, errors.NewNotFound(corev1.Resource("node"), nodeName)
 }
 
-func (*fakeSharedLister) NodeInfos() framework.NodeInfoLister {
-	return nil
+// newNodeInfo returns the NodeInfo of a node with the given allocatable
+// resources, running the given pods.
+func newNodeInfo(name string, allocatable corev1.ResourceList, pods ...*corev1.Pod) *framework.NodeInfo {
+	ni := framework.NewNodeInfo(pods...)
+	_ = ni.SetNode(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     corev1.NodeStatus{Allocatable: allocatable},
+	})
+	return ni
 }
@@ -2,9 +2,15 @@ package sample
 
 import (
 	"context"
+	"sync"
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
+	framework_rt "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
 )
 
 var _ framework.QueueSortPlugin = &TestQueueSortPlugin{}
@@ -40,3 +46,144 @@ func (t TestBindPlugin) Name() string {
 func (t TestBindPlugin) Bind(ctx context.Context, state *framework.CycleState, p *corev1.Pod, nodeName string) *framework.Status {
 	return nil
 }
+
+const waitPlugin = "wait-plugin"
+
+var _ framework.PermitPlugin = &TestWaitPlugin{}
+
+// TestWaitPlugin holds every pod in Permit until it is allowed or rejected.
+type TestWaitPlugin struct{}
+
+func newWaitPlugin(_ runtime.Object, _ framework.Handle) (framework.Plugin, error) {
+	return &TestWaitPlugin{}, nil
+}
+
+func (t TestWaitPlugin) Name() string {
+	return waitPlugin
+}
+
+func (t TestWaitPlugin) Permit(ctx context.Context, state *framework.CycleState, p *corev1.Pod, nodeName string) (*framework.Status, time.Duration) {
+	return framework.NewStatus(framework.Wait, ""), time.Minute
+}
+
+const nodeFilterPlugin = "node-filter-plugin"
+
+var _ framework.FilterPlugin = &TestNodeFilterPlugin{}
+
+// TestNodeFilterPlugin rejects the nodes it names.
+type TestNodeFilterPlugin struct {
+	rejected sets.String
+}
+
+// newNodeFilterPlugin returns the factory of a plugin rejecting nodes.
+func newNodeFilterPlugin(nodes ...string) framework_rt.PluginFactory {
+	return func(_ runtime.Object, _ framework.Handle) (framework.Plugin, error) {
+		return &TestNodeFilterPlugin{rejected: sets.NewString(nodes...)}, nil
+	}
+}
+
+func (t TestNodeFilterPlugin) Name() string {
+	return nodeFilterPlugin
+}
+
+func (t TestNodeFilterPlugin) Filter(ctx context.Context, state *framework.CycleState, p *corev1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	if t.rejected.Has(nodeInfo.Node().Name) {
+		return framework.NewStatus(framework.Unschedulable, "node is rejected")
+	}
+	return nil
+}
+
+var _ framework.PodNominator = &fakePodNominator{}
+
+// fakePodNominator keeps the nominated pods by node, as the scheduling queue
+// does.
+type fakePodNominator struct {
+	lock  sync.Mutex
+	nodes map[string][]*framework.PodInfo
+}
+
+func newFakePodNominator() *fakePodNominator {
+	return &fakePodNominator{nodes: map[string][]*framework.PodInfo{}}
+}
+
+func (n *fakePodNominator) AddNominatedPod(pod *framework.PodInfo, nodeName string) {
+	n.DeleteNominatedPodIfExists(pod.Pod)
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.nodes[nodeName] = append(n.nodes[nodeName], pod)
+}
+
+func (n *fakePodNominator) DeleteNominatedPodIfExists(pod *corev1.Pod) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for node, pods := range n.nodes {
+		for i, p := range pods {
+			if p.Pod.UID == pod.UID {
+				n.nodes[node] = append(pods[:i:i], pods[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (n *fakePodNominator) UpdateNominatedPod(oldPod *corev1.Pod, newPodInfo *framework.PodInfo) {
+	n.DeleteNominatedPodIfExists(oldPod)
+	if newPodInfo.Pod.Status.NominatedNodeName != "" {
+		n.AddNominatedPod(newPodInfo, newPodInfo.Pod.Status.NominatedNodeName)
+	}
+}
+
+func (n *fakePodNominator) NominatedPodsForNode(nodeName string) []*framework.PodInfo {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return append([]*framework.PodInfo{}, n.nodes[nodeName]...)
+}
+
+// newFakeSample returns a Sample reading groups from the fake listers, with
+// the fake pods as members.
+func newFakeSample(handle framework.Handle) *Sample {
+	s := &Sample{
+		handle:        handle,
+		args:          defaultArgs(),
+		cmLister:      &fakeConfigMapLister{},
+		gangs:         newGangManager(),
+		queues:        newQueueManager(),
+		status:        newStatusUpdater(nil),
+		capacityCache: newCapacityCache(),
+		denials:       newDenialCache(),
+		reclaims:      newReclaimCache(),
+		backoff:       newGroupBackoff(2*time.Second, 2*time.Minute),
+		events:        newEventRecorder(handle.EventRecorder()),
+	}
+	addPods(s, fakePods...)
+	return s
+}
+
+// addPods records pods as members of their groups, as the pod informer does.
+func addPods(s *Sample, pods ...*corev1.Pod) {
+	for _, p := range pods {
+		key := ""
+		if name := s.groupName(p); name != "" {
+			key = p.Namespace + "/" + name
+		}
+		s.gangs.setPod(key, p)
+	}
+}
+
+// pendingMembers returns pods of the group that are not bound yet.
+func pendingMembers(group string, names ...string) []*corev1.Pod {
+	pods := make([]*corev1.Pod, 0, len(names))
+	for _, name := range names {
+		pods = append(pods, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{PodGroupName: group}}})
+	}
+	return pods
+}
+
+// terminating marks pods as being deleted.
+func terminating(pods ...*corev1.Pod) []*corev1.Pod {
+	now := metav1.Now()
+	for _, p := range pods {
+		p.DeletionTimestamp = &now
+	}
+	return pods
+}
@@ -0,0 +1,292 @@
+package sample
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// DebugPath is the path the live state of the groups is served at.
+const DebugPath = "/debug/gangs"
+
+// DebugServer serves the live state of the groups of every profile of one
+// scheduler running the plugin on DebugPath of its own listener, see
+// NewFactory. Nothing is served while Address is empty.
+type DebugServer struct {
+	// Address is the address to listen on, set before the plugins are made.
+	Address string
+
+	lock sync.RWMutex
+	// samples are the plugins of every profile, in the order they were made.
+	samples []*Sample
+	// addr is the address listened on, nil until the listener is started.
+	addr net.Addr
+}
+
+// NewDebugServer returns a DebugServer serving nothing until its Address is
+// set.
+func NewDebugServer() *DebugServer {
+	return &DebugServer{}
+}
+
+// register adds the plugin of a profile. The listener is started with the
+// first one, and closed when stopCh is.
+func (d *DebugServer) register(s *Sample, stopCh <-chan struct{}) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.samples = append(d.samples, s)
+	if len(d.samples) > 1 || d.Address == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", d.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %v for %v: %v", d.Address, DebugPath, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(DebugPath, newDebugHandler(d.registered))
+	server := &http.Server{Handler: mux}
+	go func() {
+		<-stopCh
+		server.Close()
+	}()
+	go func() {
+		if err := server.Serve(listener); err != http.ErrServerClosed {
+			klog.Errorf("failed to serve %v: %v", DebugPath, err)
+		}
+	}()
+	d.addr = listener.Addr()
+	klog.Infof("serving %v on %v", DebugPath, d.addr)
+	return nil
+}
+
+func (d *DebugServer) registered() []*Sample {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return append([]*Sample(nil), d.samples...)
+}
+
+// debugGroups is the body served on DebugPath.
+type debugGroups struct {
+	Groups []debugGroup `json:"groups"`
+	// Queues are left out when the groups of one namespace are asked for.
+	Queues []debugQueue `json:"queues,omitempty"`
+}
+
+// debugQueue is a Queue with the resources used by its pods.
+type debugQueue struct {
+	Name string          `json:"name"`
+	Min  v1.ResourceList `json:"min,omitempty"`
+	Max  v1.ResourceList `json:"max,omitempty"`
+	Used v1.ResourceList `json:"used"`
+	// Borrowed is what the queue uses beyond its min, its pods may be
+	// preempted to give it back.
+	Borrowed v1.ResourceList `json:"borrowed,omitempty"`
+}
+
+// debugGroup is the live state of a group as seen by the scheduler.
+type debugGroup struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Config is nil when the group can not be looked up, Error tells why.
+	Config  *debugGroupConfig `json:"config,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	Members debugMemberCounts `json:"members"`
+	// Roles are the member counts of each role the group sets a minimum for.
+	Roles map[string]debugRoleCounts `json:"roles,omitempty"`
+	// Domain is the topology domain chosen for a group requiring one.
+	Domain string `json:"domain,omitempty"`
+	// Waiting are the members held in Permit.
+	Waiting                 []debugWaitingPod `json:"waiting"`
+	BackoffRemainingSeconds float64           `json:"backoffRemainingSeconds,omitempty"`
+}
+
+type debugGroupConfig struct {
+	Source                 string         `json:"source"`
+	MinAvailable           int            `json:"minAvailable"`
+	MaxMember              int            `json:"maxMember,omitempty"`
+	MinRoles               map[string]int `json:"minRoles,omitempty"`
+	Placement              string         `json:"placement"`
+	RequiredTopologyKeys   []string       `json:"requiredTopologyKeys,omitempty"`
+	ScheduleTimeoutSeconds float64        `json:"scheduleTimeoutSeconds"`
+	PriorityClassName      string         `json:"priorityClassName,omitempty"`
+}
+
+type debugMemberCounts struct {
+	Total       int `json:"total"`
+	Terminating int `json:"terminating"`
+	Pending     int `json:"pending"`
+	Bound       int `json:"bound"`
+	Assumed     int `json:"assumed"`
+	Running     int `json:"running"`
+	Succeeded   int `json:"succeeded"`
+	Failed      int `json:"failed"`
+}
+
+type debugRoleCounts struct {
+	Pending int `json:"pending"`
+	Bound   int `json:"bound"`
+	Waiting int `json:"waiting"`
+}
+
+type debugWaitingPod struct {
+	Name string `json:"name"`
+	Role string `json:"role,omitempty"`
+	// NodeName is the node reserved for the member.
+	NodeName string `json:"nodeName"`
+	// RemainingSeconds is how long until the wait of the member times out,
+	// nil if it is not held by this plugin.
+	RemainingSeconds *float64 `json:"remainingSeconds,omitempty"`
+}
+
+// newDebugHandler returns the handler serving the live state of the groups of
+// the plugins samples returns as JSON: their configuration, member counts,
+// and the members held in Permit with their reserved node and the time left
+// before they time out, then the Queues with the resources their pods use.
+// The namespace query parameter restricts it to the groups of one namespace.
+func newDebugHandler(samples func() []*Sample) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		namespace := req.URL.Query().Get("namespace")
+		now := time.Now()
+		body := debugGroups{Groups: []debugGroup{}}
+		for _, s := range samples() {
+			body.Groups = append(body.Groups, s.debugGroups(namespace, now)...)
+			if namespace == "" {
+				body.Queues = append(body.Queues, s.debugQueues()...)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			klog.V(3).Infof("failed to write %v: %v", DebugPath, err)
+		}
+	})
+}
+
+// debugQueues returns the Queues sorted by name, with the resources used by
+// their pods.
+func (s *Sample) debugQueues() []debugQueue {
+	if s.queueLister == nil {
+		return nil
+	}
+	queues, err := s.queueLister.List(labels.Everything())
+	if err != nil {
+		return nil
+	}
+	sort.Slice(queues, func(i, j int) bool { return queues[i].Name < queues[j].Name })
+	var body []debugQueue
+	for _, q := range queues {
+		used := s.queues.usedBy(q.Name)
+		if used == nil {
+			used = v1.ResourceList{}
+		}
+		borrowed := v1.ResourceList{}
+		for _, name := range beyondMin(v1.ResourceList{}, used, q) {
+			over := used[name].DeepCopy()
+			over.Sub(q.Spec.Min[name])
+			borrowed[name] = over
+		}
+		body = append(body, debugQueue{Name: q.Name, Min: q.Spec.Min, Max: q.Spec.Max, Used: used, Borrowed: borrowed})
+	}
+	return body
+}
+
+// debugGroups returns the state of the groups of namespace, or of every
+// namespace when it is empty, sorted by namespace and name.
+func (s *Sample) debugGroups(namespace string, now time.Time) []debugGroup {
+	// The members held in Permit as the framework sees them, by group.
+	waitingPods := map[string][]framework.WaitingPod{}
+	s.handle.IterateOverWaitingPods(func(waitingPod framework.WaitingPod) {
+		pod := waitingPod.GetPod()
+		if name := s.groupName(pod); name != "" {
+			key := pod.Namespace + "/" + name
+			waitingPods[key] = append(waitingPods[key], waitingPod)
+		}
+	})
+	keys := s.gangs.keys()
+	for key := range waitingPods {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var groups []debugGroup
+	for i, key := range keys {
+		if i > 0 && keys[i-1] == key {
+			continue
+		}
+		ns, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil || (namespace != "" && ns != namespace) {
+			continue
+		}
+		counts := s.gangs.counts(key)
+		group := debugGroup{
+			Namespace: ns,
+			Name:      name,
+			Members: debugMemberCounts{
+				Total:       counts.total,
+				Terminating: counts.terminating,
+				Pending:     counts.pending,
+				Bound:       counts.bound,
+				Assumed:     counts.assumed,
+				Running:     counts.running,
+				Succeeded:   counts.succeeded,
+				Failed:      counts.failed,
+			},
+			Waiting:                 []debugWaitingPod{},
+			BackoffRemainingSeconds: s.backoff.remaining(key).Seconds(),
+		}
+		if pg, err := s.getPodGroup(ns, name); err != nil {
+			group.Error = err.Error()
+		} else {
+			group.Config = &debugGroupConfig{
+				Source:                 pg.source,
+				MinAvailable:           pg.minAvailable,
+				MaxMember:              pg.maxMember,
+				ScheduleTimeoutSeconds: pg.scheduleTimeout.Seconds(),
+				MinRoles:               pg.minRoles,
+				Placement:              string(pg.placement),
+				RequiredTopologyKeys:   pg.requiredTopologyKeys,
+				PriorityClassName:      pg.priorityClassName,
+			}
+			if len(pg.minRoles) > 0 {
+				roles := s.gangs.roleCounts(key, s.args.RoleLabelKey)
+				group.Roles = make(map[string]debugRoleCounts, len(pg.minRoles))
+				for role := range pg.minRoles {
+					c := roles[role]
+					group.Roles[role] = debugRoleCounts{Pending: c.pending, Bound: c.bound, Waiting: c.waiting}
+				}
+			}
+		}
+		if d := s.gangs.domain(key); d != nil {
+			group.Domain = d.String()
+		}
+		deadlines := s.gangs.waitingDeadlines(key)
+		for _, waitingPod := range waitingPods[key] {
+			pod := waitingPod.GetPod()
+			w := debugWaitingPod{Name: pod.Name, Role: s.role(pod), NodeName: pod.Spec.NodeName}
+			if deadline, exist := deadlines[pod.Name]; exist {
+				remaining := deadline.Sub(now).Seconds()
+				if remaining < 0 {
+					remaining = 0
+				}
+				w.RemainingSeconds = &remaining
+			}
+			group.Waiting = append(group.Waiting, w)
+		}
+		sort.Slice(group.Waiting, func(i, j int) bool { return group.Waiting[i].Name < group.Waiting[j].Name })
+		groups = append(groups, group)
+	}
+	return groups
+}
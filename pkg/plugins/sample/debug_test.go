@@ -0,0 +1,134 @@
+package sample
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	framework_rt "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+)
+
+func TestDebugHandler(t *testing.T) {
+	var s *Sample
+	registry := framework_rt.Registry{}
+	if err := registry.Register(Name, func(_ runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+		s = newFakeSample(handle)
+		return s, nil
+	}); err != nil {
+		t.Fatalf("fail to register permit plugin (%s)", Name)
+	}
+	cfgPls := &config.Plugins{Permit: config.PluginSet{Enabled: []config.Plugin{{Name: Name}}}}
+	f, err := newFrameworkWithQueueSortAndBind(registry, cfgPls, emptyArgs, framework_rt.WithSnapshotSharedLister(&fakeSharedLister{}))
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+	// The waiting pods are the assumed ones, on their reserved node.
+	member := func(name, namespace, group string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, UID: types.UID(namespace + name),
+				Labels: map[string]string{PodGroupName: group}},
+			Spec: corev1.PodSpec{NodeName: "node1"},
+		}
+	}
+	for _, p := range []*corev1.Pod{member("pod1", "", "pg1"), member("pod2", "", "pg1")} {
+		if got := f.RunPermitPlugins(context.TODO(), nil, p, "node1"); got.Code() != framework.Wait {
+			t.Fatalf("expected %v to wait, got %v", p.Name, got.Code())
+		}
+	}
+
+	get := func(url string) debugGroups {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		newDebugHandler(func() []*Sample { return []*Sample{s} }).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %v, got %v", http.StatusOK, rec.Code)
+		}
+		var body debugGroups
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("fail to decode %q: %v", rec.Body.String(), err)
+		}
+		return body
+	}
+
+	body := get(DebugPath)
+	if len(body.Groups) != 2 {
+		t.Fatalf("expected 2 groups, got %+v", body.Groups)
+	}
+	pg1, pg2 := body.Groups[0], body.Groups[1]
+	if pg1.Name != "pg1" || pg2.Name != "pg2" {
+		t.Fatalf("expected pg1 and pg2, got %v and %v", pg1.Name, pg2.Name)
+	}
+	if pg1.Config == nil || pg1.Config.MinAvailable != 3 || pg1.Config.Source != "ConfigMap" {
+		t.Errorf("expected the config of pg1, got %+v", pg1.Config)
+	}
+	if len(pg1.Waiting) != 2 {
+		t.Fatalf("expected 2 waiting members of pg1, got %+v", pg1.Waiting)
+	}
+	for i, name := range []string{"pod1", "pod2"} {
+		w := pg1.Waiting[i]
+		if w.Name != name || w.NodeName != "node1" {
+			t.Errorf("expected %v waiting on node1, got %+v", name, w)
+		}
+		if w.RemainingSeconds == nil || *w.RemainingSeconds <= 0 || *w.RemainingSeconds > pg1.Config.ScheduleTimeoutSeconds {
+			t.Errorf("expected the remaining wait of %v within the timeout, got %v", name, w.RemainingSeconds)
+		}
+	}
+	if pg1.Members.Total != 1 || pg1.Members.Pending != 1 {
+		t.Errorf("expected the pending member of pg1, got %+v", pg1.Members)
+	}
+	if pg2.Members.Total != 3 || pg2.Members.Bound != 3 || pg2.Members.Running != 3 || len(pg2.Waiting) != 0 {
+		t.Errorf("expected the running members of pg2, got %+v", pg2)
+	}
+
+	body = get(DebugPath + "?namespace=namespace-without-groups")
+	if len(body.Groups) != 0 {
+		t.Errorf("expected no group, got %+v", body.Groups)
+	}
+
+	rec := httptest.NewRecorder()
+	newDebugHandler(func() []*Sample { return []*Sample{s} }).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, DebugPath, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %v, got %v", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
+
+func TestDebugServer(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	d := NewDebugServer()
+	d.Address = "127.0.0.1:0"
+	// The plugins of two profiles are served on one listener.
+	for i := 0; i < 2; i++ {
+		registry := framework_rt.Registry{}
+		if err := registry.Register(Name, func(_ runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+			s := newFakeSample(handle)
+			return s, d.register(s, stopCh)
+		}); err != nil {
+			t.Fatalf("fail to register permit plugin (%s)", Name)
+		}
+		cfgPls := &config.Plugins{Permit: config.PluginSet{Enabled: []config.Plugin{{Name: Name}}}}
+		if _, err := newFrameworkWithQueueSortAndBind(registry, cfgPls, emptyArgs, framework_rt.WithSnapshotSharedLister(&fakeSharedLister{})); err != nil {
+			t.Fatalf("fail to create the framework of profile %d: %s", i, err)
+		}
+	}
+	resp, err := http.Get("http://" + d.addr.String() + DebugPath)
+	if err != nil {
+		t.Fatalf("fail to get %v: %v", DebugPath, err)
+	}
+	defer resp.Body.Close()
+	var body debugGroups
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected groups, got %v: %v", resp.Status, err)
+	}
+	if got := len(d.registered()); got != 2 {
+		t.Errorf("expected the plugins of 2 profiles, got %v", got)
+	}
+}
@@ -0,0 +1,73 @@
+package sample
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/tools/events"
+)
+
+// Reasons of the events about groups.
+const (
+	reasonWaitingForQuorum = "WaitingForQuorum"
+	reasonQuorumReached    = "QuorumReached"
+	reasonTimedOut         = "GangTimedOut"
+	reasonNotFound         = "PodGroupNotFound"
+	reasonInvalid          = "InvalidPodGroup"
+	reasonBorrowing        = "BorrowingQuota"
+	reasonReclaiming       = "ReclaimingQuota"
+	reasonReclaimed        = "QuotaReclaimed"
+
+	eventAction = "Scheduling"
+
+	// An event identical to one recorded less than eventDedupTTL ago is
+	// dropped, as members are retried much more often.
+	eventDedupTTL  = 5 * time.Minute
+	eventDedupSize = 4096
+)
+
+// eventRecorder records events on members and groups through the recorder
+// of the framework, dropping the identical ones.
+type eventRecorder struct {
+	recorder events.EventRecorder
+	recent   *utilcache.LRUExpireCache
+}
+
+func newEventRecorder(recorder events.EventRecorder) *eventRecorder {
+	return &eventRecorder{recorder: recorder, recent: utilcache.NewLRUExpireCache(eventDedupSize)}
+}
+
+// podEvent records an event on pod.
+func (r *eventRecorder) podEvent(pod *v1.Pod, eventtype, reason, note string) {
+	r.event(pod, "Pod/"+string(pod.UID)+"/"+pod.Namespace+"/"+pod.Name, eventtype, reason, note)
+}
+
+// groupEvent records an event on the object declaring pg.
+func (r *eventRecorder) groupEvent(pg *podGroup, eventtype, reason, note string) {
+	if pg == nil {
+		return
+	}
+	r.refEvent(pg.ref, eventtype, reason, note)
+}
+
+// refEvent records an event on the object of ref, if any.
+func (r *eventRecorder) refEvent(ref *v1.ObjectReference, eventtype, reason, note string) {
+	if ref == nil {
+		return
+	}
+	r.event(ref, ref.Kind+"/"+string(ref.UID)+"/"+ref.Namespace+"/"+ref.Name, eventtype, reason, note)
+}
+
+func (r *eventRecorder) event(regarding runtime.Object, objKey, eventtype, reason, note string) {
+	if r == nil || r.recorder == nil {
+		return
+	}
+	key := objKey + "/" + eventtype + "/" + reason + "/" + note
+	if _, recent := r.recent.Get(key); recent {
+		return
+	}
+	r.recent.Add(key, struct{}{}, eventDedupTTL)
+	r.recorder.Eventf(regarding, nil, eventtype, reason, eventAction, "%s", note)
+}
@@ -0,0 +1,122 @@
+package sample
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	framework_rt "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+)
+
+// recordedEvents drains the events recorded so far.
+func recordedEvents(recorder *events.FakeRecorder) []string {
+	var got []string
+	for {
+		select {
+		case e := <-recorder.Events:
+			got = append(got, e)
+		default:
+			return got
+		}
+	}
+}
+
+func TestEventRecorderDedup(t *testing.T) {
+	recorder := events.NewFakeRecorder(10)
+	r := newEventRecorder(recorder)
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns", UID: types.UID("pod1")}}
+	other := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "ns", UID: types.UID("pod2")}}
+
+	r.podEvent(pod, corev1.EventTypeNormal, reasonWaitingForQuorum, "waiting(1)")
+	r.podEvent(pod, corev1.EventTypeNormal, reasonWaitingForQuorum, "waiting(1)")
+	r.podEvent(pod, corev1.EventTypeNormal, reasonWaitingForQuorum, "waiting(2)")
+	r.podEvent(other, corev1.EventTypeNormal, reasonWaitingForQuorum, "waiting(1)")
+	expected := []string{
+		"Normal WaitingForQuorum waiting(1)",
+		"Normal WaitingForQuorum waiting(2)",
+		"Normal WaitingForQuorum waiting(1)",
+	}
+	got := recordedEvents(recorder)
+	if len(got) != len(expected) {
+		t.Fatalf("expected events %q, got %q", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("expected event %q, got %q", expected[i], got[i])
+		}
+	}
+
+	// Nothing is recorded without a recorder.
+	newEventRecorder(nil).podEvent(pod, corev1.EventTypeNormal, reasonWaitingForQuorum, "waiting(1)")
+}
+
+func TestGangEvents(t *testing.T) {
+	recorder := events.NewFakeRecorder(100)
+	f, err := newFrameworkWithQueueSortAndBind(framework_rt.Registry{}, &config.Plugins{}, emptyArgs,
+		framework_rt.WithSnapshotSharedLister(&fakeSharedLister{}), framework_rt.WithEventRecorder(recorder))
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+	s := newFakeSample(f)
+	s.cmLister = clientv1.NewConfigMapLister(newIndexer(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "gang", Namespace: "ns"}, Data: map[string]string{minAvailable: "3"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "negative", Namespace: "ns"}, Data: map[string]string{minAvailable: "-1"}},
+	))
+	member := func(name, group string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: "ns", UID: types.UID(name), Labels: map[string]string{PodGroupName: group},
+		}}
+	}
+	// reasons returns the reasons of the events recorded so far.
+	reasons := func() []string {
+		var got []string
+		for _, e := range recordedEvents(recorder) {
+			fields := strings.SplitN(e, " ", 3)
+			got = append(got, fields[0]+" "+fields[1])
+		}
+		return got
+	}
+	expect := func(what string, expected ...string) {
+		t.Helper()
+		got := reasons()
+		if len(got) != len(expected) {
+			t.Fatalf("%v: expected events %q, got %q", what, expected, got)
+		}
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Errorf("%v: expected event %q, got %q", what, expected[i], got[i])
+			}
+		}
+	}
+
+	s.PreFilter(context.TODO(), framework.NewCycleState(), member("pod1", "missing"))
+	expect("missing group", "Warning PodGroupNotFound")
+	s.PreFilter(context.TODO(), framework.NewCycleState(), member("pod1", "missing"))
+	expect("missing group again")
+
+	s.PreFilter(context.TODO(), framework.NewCycleState(), member("pod1", "negative"))
+	expect("invalid minAvailable", "Warning InvalidPodGroup", "Warning InvalidPodGroup")
+
+	for _, name := range []string{"pod1", "pod2"} {
+		s.Permit(context.TODO(), nil, member(name, "gang"), "")
+	}
+	expect("waiting for quorum", "Normal WaitingForQuorum", "Normal WaitingForQuorum")
+	s.Permit(context.TODO(), nil, member("pod3", "gang"), "")
+	expect("quorum reached", "Normal QuorumReached", "Normal QuorumReached")
+
+	timeout := member("pod1", "other")
+	s.cmLister = clientv1.NewConfigMapLister(newIndexer(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns"}, Data: map[string]string{minAvailable: "2"}},
+	))
+	s.Permit(context.TODO(), nil, timeout, "")
+	expect("waiting for quorum", "Normal WaitingForQuorum")
+	s.Unreserve(context.TODO(), nil, timeout, "")
+	expect("timed out", "Warning GangTimedOut", "Warning GangTimedOut")
+}
@@ -0,0 +1,661 @@
+package sample
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+)
+
+// gangManager keeps the members of every group and their counts in memory,
+// fed by the pod informer, so that the extension points never list pods. It
+// also caches what is looked up about a group, until the informers of the
+// objects declaring groups invalidate it. Groups are keyed by namespace/name.
+type gangManager struct {
+	lock  sync.RWMutex
+	gangs map[string]*gang
+	// podGangs is the group key of every member, by pod key, so that a pod
+	// leaves its old group when its labels change.
+	podGangs map[string]string
+}
+
+// gang is what is known about one group.
+type gang struct {
+	// members are the pods of the group by name, they all live in the
+	// namespace of the group.
+	members map[string]*v1.Pod
+	counts  memberCounts
+	// waiting are the deadlines of the members held in Permit by name, since
+	// waitingSince when there are any. They are only changed through
+	// insertWaiting and deleteWaiting, which keep the waiting metrics.
+	waiting      map[string]time.Time
+	waitingSince time.Time
+	// rejected are the names of the members the plugin rejected in Permit
+	// whose Unreserve has not run yet. rollingBack is set once an attempt of
+	// the group is rolled back, until a member is held in Permit again, so
+	// that the attempt is rolled back only once.
+	rejected    sets.String
+	rollingBack bool
+	// assumed are the names of the members allowed in Permit whose binding
+	// the pod informer has not seen yet. The scheduler assumes them on their
+	// node meanwhile, so they count as bound.
+	assumed sets.String
+	// domain is the topology domain chosen for the members of a group
+	// requiring one, kept until the group has no members left.
+	domain *requiredDomain
+
+	// pg is the cached group, nil if it is not cached. notFound caches a
+	// group that no source declares.
+	pg       *podGroup
+	notFound bool
+	// sortKey is the cached queue sort key of the members.
+	sortKey *queueSortKey
+	// generation is bumped on every invalidation of the group, a lookup
+	// started before is not cached.
+	generation uint64
+	// priority is the priority of the first member of the group, frozen
+	// while it has members so that the sort key of members already queued
+	// does not change under the queue.
+	priority *int32
+}
+
+// memberCounts are the numbers of members of a group in each state. Every
+// member is in exactly one of terminating, pending, bound, succeeded and
+// failed, so that a member is never counted twice nor left out.
+type memberCounts struct {
+	total int
+	// terminating members are being deleted, they count for nothing else
+	// whatever their phase.
+	terminating int
+	// pending members are neither bound to a node nor assumed on one yet.
+	pending int
+	// bound members are bound to a node, or assumed on one by the scheduler
+	// while their binding is in flight, and have not finished. They may
+	// still be pulling their images. running are the bound members whose
+	// containers started, assumed the bound members the pod informer has not
+	// seen bound yet.
+	bound, running, assumed int
+	succeeded, failed       int
+}
+
+func (c *memberCounts) add(pod *v1.Pod, assumed bool, delta int) {
+	c.total += delta
+	switch {
+	case pod.DeletionTimestamp != nil:
+		c.terminating += delta
+	case pod.Status.Phase == v1.PodSucceeded:
+		c.succeeded += delta
+	case pod.Status.Phase == v1.PodFailed:
+		c.failed += delta
+	case pod.Spec.NodeName == "" && !assumed:
+		c.pending += delta
+	default:
+		c.bound += delta
+		if pod.Spec.NodeName == "" {
+			c.assumed += delta
+		}
+		if pod.Status.Phase == v1.PodRunning {
+			c.running += delta
+		}
+	}
+}
+
+// scheduled returns the number of members that got their node, the ones
+// that succeeded included when countSucceeded is set.
+func (c memberCounts) scheduled(countSucceeded bool) int {
+	if countSucceeded {
+		return c.bound + c.succeeded
+	}
+	return c.bound
+}
+
+// available returns the number of members that may still be part of the
+// quorum.
+func (c memberCounts) available(countSucceeded bool) int {
+	return c.pending + c.scheduled(countSucceeded)
+}
+
+func newGangManager() *gangManager {
+	return &gangManager{
+		gangs:    map[string]*gang{},
+		podGangs: map[string]string{},
+	}
+}
+
+func podKey(pod *v1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
+
+// getOrCreate returns the gang of key, creating it. The lock must be held.
+func (m *gangManager) getOrCreate(key string) *gang {
+	g, exist := m.gangs[key]
+	if !exist {
+		g = &gang{members: map[string]*v1.Pod{}, waiting: map[string]time.Time{}, assumed: sets.NewString(), rejected: sets.NewString()}
+		m.gangs[key] = g
+	}
+	return g
+}
+
+// gc forgets the gang of key once nothing is left in it. The lock must be held.
+func (m *gangManager) gc(key string) {
+	if g, exist := m.gangs[key]; exist && len(g.members) == 0 && len(g.waiting) == 0 && g.assumed.Len() == 0 && g.rejected.Len() == 0 && g.pg == nil && !g.notFound {
+		delete(m.gangs, key)
+	}
+}
+
+// setPod records pod as a member of the group key, or as a member of no
+// group when key is empty.
+func (m *gangManager) setPod(key string, pod *v1.Pod) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if old, exist := m.podGangs[podKey(pod)]; exist && old == key {
+		// Updated in place, the member keeps its Permit state.
+		g := m.gangs[key]
+		g.counts.add(g.members[pod.Name], g.assumed.Has(pod.Name), -1)
+	} else {
+		m.removePod(podKey(pod))
+	}
+	if key == "" {
+		return
+	}
+	g := m.getOrCreate(key)
+	if len(g.members) == 0 {
+		// The priority of a group naming no class is the one of its first
+		// member.
+		g.priority = pod.Spec.Priority
+		g.generation++
+		g.sortKey = nil
+	}
+	if pod.Spec.NodeName != "" || pod.DeletionTimestamp != nil {
+		// A bound member is not held in Permit, nor assumed anymore.
+		g.deleteWaiting(key, pod.Name)
+		g.assumed.Delete(pod.Name)
+	}
+	g.members[pod.Name] = pod
+	g.counts.add(pod, g.assumed.Has(pod.Name), 1)
+	m.podGangs[podKey(pod)] = key
+}
+
+// deletePod forgets pod.
+func (m *gangManager) deletePod(pod *v1.Pod) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.removePod(podKey(pod))
+}
+
+// removePod removes the pod of podKey from its group. The lock must be held.
+func (m *gangManager) removePod(podKey string) {
+	key, exist := m.podGangs[podKey]
+	if !exist {
+		return
+	}
+	delete(m.podGangs, podKey)
+	g := m.gangs[key]
+	_, name, _ := cache.SplitMetaNamespaceKey(podKey)
+	if old, exist := g.members[name]; exist {
+		g.counts.add(old, g.assumed.Has(name), -1)
+		delete(g.members, name)
+	}
+	g.deleteWaiting(key, name)
+	g.assumed.Delete(name)
+	g.rejected.Delete(name)
+	if len(g.members) == 0 {
+		g.domain = nil
+	}
+	m.gc(key)
+}
+
+// members returns the members of the group key.
+func (m *gangManager) members(key string) []*v1.Pod {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	g, exist := m.gangs[key]
+	if !exist {
+		return nil
+	}
+	pods := make([]*v1.Pod, 0, len(g.members))
+	for _, p := range g.members {
+		pods = append(pods, p)
+	}
+	return pods
+}
+
+// counts returns the member counts of the group key.
+func (m *gangManager) counts(key string) memberCounts {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if g, exist := m.gangs[key]; exist {
+		return g.counts
+	}
+	return memberCounts{}
+}
+
+// setAssumed records whether the member name of the group key is assumed.
+// The lock must be held.
+func (g *gang) setAssumed(name string, assumed bool) {
+	if g.assumed.Has(name) == assumed {
+		return
+	}
+	pod, member := g.members[name]
+	if member {
+		g.counts.add(pod, !assumed, -1)
+	}
+	if assumed {
+		g.assumed.Insert(name)
+	} else {
+		g.assumed.Delete(name)
+	}
+	if member {
+		g.counts.add(pod, assumed, 1)
+	}
+}
+
+// setWaiting records that the member name of the group key is held in Permit
+// for timeout.
+func (m *gangManager) setWaiting(key, name string, timeout time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	g := m.getOrCreate(key)
+	g.insertWaiting(key, name, time.Now().Add(timeout))
+	g.rollingBack = false
+}
+
+// admit records that the members of the group key held in Permit and the
+// member name are allowed, they are assumed until their binding is seen. It
+// returns how long the first member was held, and false if none was.
+func (m *gangManager) admit(key, name string) (time.Duration, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	g := m.getOrCreate(key)
+	g.rollingBack = false
+	waited, held := time.Since(g.waitingSince), len(g.waiting) > 0
+	for waiting := range g.waiting {
+		g.deleteWaiting(key, waiting)
+		g.setAssumed(waiting, true)
+	}
+	g.setAssumed(name, true)
+	return waited, held
+}
+
+// unreserve records that the member name of the group key is neither held
+// in Permit nor assumed anymore. It returns whether it was held in Permit,
+// and whether the plugin rejected it.
+func (m *gangManager) unreserve(key, name string) (bool, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	g, exist := m.gangs[key]
+	if !exist {
+		return false, false
+	}
+	waiting := g.deleteWaiting(key, name)
+	rejected := g.rejected.Has(name)
+	g.rejected.Delete(name)
+	g.setAssumed(name, false)
+	m.gc(key)
+	return waiting, rejected
+}
+
+// rollBack records that the current attempt of the group key is rolled back:
+// its members held in Permit are rejected by the plugin. It returns their
+// names, and false if the attempt is already rolled back.
+func (m *gangManager) rollBack(key string) (sets.String, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	g := m.getOrCreate(key)
+	if g.rollingBack {
+		return nil, false
+	}
+	g.rollingBack = true
+	names := sets.NewString()
+	for name := range g.waiting {
+		g.deleteWaiting(key, name)
+		g.rejected.Insert(name)
+		names.Insert(name)
+	}
+	m.gc(key)
+	return names, true
+}
+
+// insertWaiting records that the member name of the group key is held in
+// Permit until deadline. The lock must be held.
+func (g *gang) insertWaiting(key, name string, deadline time.Time) {
+	if _, exist := g.waiting[name]; exist {
+		g.waiting[name] = deadline
+		return
+	}
+	namespace, _, _ := cache.SplitMetaNamespaceKey(key)
+	if len(g.waiting) == 0 {
+		g.waitingSince = time.Now()
+		waitingGroups.WithLabelValues(namespace).Inc()
+	}
+	g.waiting[name] = deadline
+	waitingPods.WithLabelValues(namespace).Inc()
+}
+
+// deleteWaiting records that the member name of the group key is not held in
+// Permit, and returns whether it was. The lock must be held.
+func (g *gang) deleteWaiting(key, name string) bool {
+	if _, exist := g.waiting[name]; !exist {
+		return false
+	}
+	namespace, _, _ := cache.SplitMetaNamespaceKey(key)
+	delete(g.waiting, name)
+	waitingPods.WithLabelValues(namespace).Dec()
+	if len(g.waiting) == 0 {
+		g.waitingSince = time.Time{}
+		waitingGroups.WithLabelValues(namespace).Dec()
+	}
+	return true
+}
+
+// waiting returns the number of members of the group key held in Permit.
+func (m *gangManager) waiting(key string) int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if g, exist := m.gangs[key]; exist {
+		return len(g.waiting)
+	}
+	return 0
+}
+
+// domain returns the topology domain chosen for the group key, nil if none is.
+func (m *gangManager) domain(key string) *requiredDomain {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if g, exist := m.gangs[key]; exist {
+		return g.domain
+	}
+	return nil
+}
+
+// setDomain records the topology domain chosen for the group key.
+func (m *gangManager) setDomain(key string, d *requiredDomain) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.getOrCreate(key).domain = d
+}
+
+// roleCounts are the member counts of one role of a group.
+type roleCounts struct {
+	memberCounts
+	// waiting is the number of members of the role held in Permit.
+	waiting int
+}
+
+// roleCounts returns the member counts of the group key by role, the role of
+// a member being its roleLabel label.
+func (m *gangManager) roleCounts(key, roleLabel string) map[string]roleCounts {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	roles := map[string]roleCounts{}
+	g, exist := m.gangs[key]
+	if !exist {
+		return roles
+	}
+	for name, pod := range g.members {
+		role := pod.Labels[roleLabel]
+		c := roles[role]
+		c.add(pod, g.assumed.Has(name), 1)
+		if _, waiting := g.waiting[name]; waiting {
+			c.waiting++
+		}
+		roles[role] = c
+	}
+	return roles
+}
+
+// keys returns the keys of the groups known.
+func (m *gangManager) keys() []string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	keys := make([]string, 0, len(m.gangs))
+	for key := range m.gangs {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// waitingDeadlines returns the deadlines of the members of the group key held
+// in Permit, by name.
+func (m *gangManager) waitingDeadlines(key string) map[string]time.Time {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	deadlines := map[string]time.Time{}
+	if g, exist := m.gangs[key]; exist {
+		for name, deadline := range g.waiting {
+			deadlines[name] = deadline
+		}
+	}
+	return deadlines
+}
+
+// priority returns the priority of the first member of the group key, nil if
+// it has none.
+func (m *gangManager) priority(key string) *int32 {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if g, exist := m.gangs[key]; exist {
+		return g.priority
+	}
+	return nil
+}
+
+// cacheToken is what is cached about a group when a lookup of it starts. The
+// outcome of the lookup is cached only if the group is neither invalidated
+// nor forgotten meanwhile.
+type cacheToken struct {
+	gang       *gang
+	generation uint64
+}
+
+// token returns the cacheToken of the group key, creating the group.
+func (m *gangManager) token(key string) cacheToken {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	g := m.getOrCreate(key)
+	return cacheToken{gang: g, generation: g.generation}
+}
+
+// current returns the group key if token is still current. The lock must be
+// held.
+func (m *gangManager) current(key string, token cacheToken) (*gang, bool) {
+	g, exist := m.gangs[key]
+	return g, exist && g == token.gang && g.generation == token.generation
+}
+
+// cachedPodGroup returns the cached group of key, and whether it is cached.
+// The token to cache a fresh lookup with is returned too.
+func (m *gangManager) cachedPodGroup(key string) (*podGroup, bool, cacheToken, error) {
+	var pg *podGroup
+	var notFound bool
+	m.lock.RLock()
+	if g, exist := m.gangs[key]; exist {
+		pg, notFound = g.pg, g.notFound
+	}
+	m.lock.RUnlock()
+	switch {
+	case pg != nil:
+		return pg, true, cacheToken{}, nil
+	case notFound:
+		_, name, _ := cache.SplitMetaNamespaceKey(key)
+		return nil, true, cacheToken{}, apierrors.NewNotFound(podGroupResource, name)
+	}
+	return nil, false, m.token(key), nil
+}
+
+// cachePodGroup caches the outcome of a lookup of the group key started with
+// token. Errors other than NotFound are not cached.
+func (m *gangManager) cachePodGroup(key string, token cacheToken, pg *podGroup, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if g, current := m.current(key, token); current {
+		switch {
+		case err == nil:
+			g.pg = pg
+		case apierrors.IsNotFound(err):
+			g.notFound = true
+		}
+	}
+	m.gc(key)
+}
+
+// cachedSortKey returns the cached queue sort key of the group key, nil if
+// it is not cached. The token to cache a fresh one with is returned too.
+func (m *gangManager) cachedSortKey(key string) (*queueSortKey, cacheToken) {
+	var sortKey *queueSortKey
+	m.lock.RLock()
+	if g, exist := m.gangs[key]; exist {
+		sortKey = g.sortKey
+	}
+	m.lock.RUnlock()
+	if sortKey != nil {
+		return sortKey, cacheToken{}
+	}
+	return nil, m.token(key)
+}
+
+// cacheSortKey caches the queue sort key of the group key computed with token.
+func (m *gangManager) cacheSortKey(key string, token cacheToken, sortKey queueSortKey) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if g, current := m.current(key, token); current {
+		g.sortKey = &sortKey
+	}
+	m.gc(key)
+}
+
+// invalidate drops what is cached about the group key.
+func (m *gangManager) invalidate(key string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if g, exist := m.gangs[key]; exist {
+		g.generation++
+		g.pg, g.notFound, g.sortKey = nil, false, nil
+		m.gc(key)
+	}
+}
+
+// invalidateAll drops what is cached about every group.
+func (m *gangManager) invalidateAll() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for key, g := range m.gangs {
+		g.generation++
+		g.pg, g.notFound, g.sortKey = nil, false, nil
+		m.gc(key)
+	}
+}
+
+// gangPodEventHandler returns the handler keeping the members of every
+// group. The status of the group is synced once its counts are updated.
+func (s *Sample) gangPodEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    s.setGangMember,
+		UpdateFunc: func(_, obj interface{}) { s.setGangMember(obj) },
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if pod, ok := obj.(*v1.Pod); ok {
+				s.gangs.deletePod(pod)
+				s.enqueuePodGroupOf(pod)
+			}
+		},
+	}
+}
+
+func (s *Sample) setGangMember(obj interface{}) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	key := ""
+	if name := s.groupName(pod); name != "" {
+		key = pod.Namespace + "/" + name
+	}
+	s.gangs.setPod(key, pod)
+	s.enqueuePodGroupOf(pod)
+}
+
+// groupObjectEventHandler returns the handler invalidating the cached group
+// when the object declaring it changes. An update invalidates it only if
+// changed reports that it changes the group.
+func (s *Sample) groupObjectEventHandler(changed func(old, new interface{}) bool) cache.ResourceEventHandler {
+	invalidate := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		if m, err := meta.Accessor(obj); err == nil {
+			s.gangs.invalidate(m.GetNamespace() + "/" + m.GetName())
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: invalidate,
+		UpdateFunc: func(old, obj interface{}) {
+			if changed(old, obj) {
+				invalidate(obj)
+			}
+		},
+		DeleteFunc: invalidate,
+	}
+}
+
+// specChanged returns whether the spec of a PodGroup, or of a group of
+// another scheduler, changes: its generation is not bumped by status updates.
+func specChanged(old, new interface{}) bool {
+	oldMeta, err := meta.Accessor(old)
+	if err != nil {
+		return true
+	}
+	newMeta, err := meta.Accessor(new)
+	if err != nil {
+		return true
+	}
+	return oldMeta.GetGeneration() != newMeta.GetGeneration()
+}
+
+// configMapEventHandler returns the handler invalidating the cached group
+// when a legacy ConfigMap declaring it changes. ConfigMaps holding no
+// minAvailable are not groups and are ignored, and so are the updates that
+// leave the data alone, e.g. of the status annotations of the plugin.
+func (s *Sample) configMapEventHandler() cache.ResourceEventHandler {
+	return cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			cm, ok := obj.(*v1.ConfigMap)
+			if !ok {
+				return false
+			}
+			_, exist := cm.Data[minAvailable]
+			return exist
+		},
+		Handler: s.groupObjectEventHandler(func(old, new interface{}) bool {
+			oldCM, ok := old.(*v1.ConfigMap)
+			if !ok {
+				return true
+			}
+			newCM, ok := new.(*v1.ConfigMap)
+			if !ok {
+				return true
+			}
+			return !reflect.DeepEqual(oldCM.Data, newCM.Data)
+		}),
+	}
+}
+
+// priorityClassEventHandler returns the handler invalidating every cached
+// group when a priority class changes, as their priorities may change.
+func (s *Sample) priorityClassEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { s.gangs.invalidateAll() },
+		UpdateFunc: func(_, _ interface{}) { s.gangs.invalidateAll() },
+		DeleteFunc: func(interface{}) { s.gangs.invalidateAll() },
+	}
+}
@@ -0,0 +1,292 @@
+package sample
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+)
+
+func TestGangManagerMembers(t *testing.T) {
+	m := newGangManager()
+	pending := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns"}}
+	running := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "ns"},
+		Spec:       corev1.PodSpec{NodeName: "node1"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	m.setPod("ns/pg", pending)
+	m.setPod("ns/pg", running)
+	m.setWaiting("ns/pg", "pod1", time.Minute)
+	if got, expected := m.counts("ns/pg"), (memberCounts{total: 2, pending: 1, bound: 1, running: 1}); got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	if got := m.waiting("ns/pg"); got != 1 {
+		t.Errorf("expected 1 waiting, got %v", got)
+	}
+
+	// Bound, the member is not held in Permit anymore.
+	bound := pending.DeepCopy()
+	bound.Spec.NodeName = "node2"
+	m.setPod("ns/pg", bound)
+	if got := m.waiting("ns/pg"); got != 0 {
+		t.Errorf("expected 0 waiting, got %v", got)
+	}
+
+	// Terminating.
+	terminating := running.DeepCopy()
+	now := metav1.Now()
+	terminating.DeletionTimestamp = &now
+	m.setPod("ns/pg", terminating)
+	if got, expected := m.counts("ns/pg"), (memberCounts{total: 2, terminating: 1, bound: 1}); got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	// Moved to another group.
+	m.setPod("ns/other", bound)
+	if got := len(m.members("ns/pg")); got != 1 {
+		t.Errorf("expected 1 member left, got %v", got)
+	}
+	if got := len(m.members("ns/other")); got != 1 {
+		t.Errorf("expected 1 member in the new group, got %v", got)
+	}
+
+	// Left every group.
+	m.setPod("", terminating)
+	m.deletePod(bound)
+	if got := len(m.gangs); got != 0 {
+		t.Errorf("expected every gang to be forgotten, got %v", got)
+	}
+}
+
+func TestMemberCounts(t *testing.T) {
+	now := metav1.Now()
+	for _, tt := range []struct {
+		name     string
+		nodeName string
+		phase    corev1.PodPhase
+		deleted  bool
+		assumed  bool
+		expected memberCounts
+		// scheduled and available are expected with the succeeded members
+		// counted, then ignored.
+		scheduled, available [2]int
+	}{
+		{name: "pending", phase: corev1.PodPending, expected: memberCounts{pending: 1}, available: [2]int{1, 1}},
+		{name: "assumed", phase: corev1.PodPending, assumed: true, expected: memberCounts{bound: 1, assumed: 1}, scheduled: [2]int{1, 1}, available: [2]int{1, 1}},
+		{name: "bound pulling images", nodeName: "node1", phase: corev1.PodPending, expected: memberCounts{bound: 1}, scheduled: [2]int{1, 1}, available: [2]int{1, 1}},
+		{name: "bound and assumed", nodeName: "node1", phase: corev1.PodPending, assumed: true, expected: memberCounts{bound: 1}, scheduled: [2]int{1, 1}, available: [2]int{1, 1}},
+		{name: "running", nodeName: "node1", phase: corev1.PodRunning, expected: memberCounts{bound: 1, running: 1}, scheduled: [2]int{1, 1}, available: [2]int{1, 1}},
+		{name: "unknown", nodeName: "node1", phase: corev1.PodUnknown, expected: memberCounts{bound: 1}, scheduled: [2]int{1, 1}, available: [2]int{1, 1}},
+		{name: "succeeded", nodeName: "node1", phase: corev1.PodSucceeded, expected: memberCounts{succeeded: 1}, scheduled: [2]int{1, 0}, available: [2]int{1, 0}},
+		{name: "failed", nodeName: "node1", phase: corev1.PodFailed, expected: memberCounts{failed: 1}},
+		{name: "terminating pending", phase: corev1.PodPending, deleted: true, expected: memberCounts{terminating: 1}},
+		{name: "terminating running", nodeName: "node1", phase: corev1.PodRunning, deleted: true, expected: memberCounts{terminating: 1}},
+		{name: "terminating succeeded", nodeName: "node1", phase: corev1.PodSucceeded, deleted: true, expected: memberCounts{terminating: 1}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns"},
+				Spec:       corev1.PodSpec{NodeName: tt.nodeName},
+				Status:     corev1.PodStatus{Phase: tt.phase},
+			}
+			if tt.deleted {
+				pod.DeletionTimestamp = &now
+			}
+			var got memberCounts
+			got.add(pod, tt.assumed, 1)
+			expected := tt.expected
+			expected.total = 1
+			if got != expected {
+				t.Errorf("expected %+v, got %+v", expected, got)
+			}
+			for i, countSucceeded := range []bool{true, false} {
+				if n := got.scheduled(countSucceeded); n != tt.scheduled[i] {
+					t.Errorf("expected %v scheduled when counting succeeded is %v, got %v", tt.scheduled[i], countSucceeded, n)
+				}
+				if n := got.available(countSucceeded); n != tt.available[i] {
+					t.Errorf("expected %v available when counting succeeded is %v, got %v", tt.available[i], countSucceeded, n)
+				}
+			}
+			got.add(pod, tt.assumed, -1)
+			if got != (memberCounts{}) {
+				t.Errorf("expected the member to be removed, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestGangManagerAdmit(t *testing.T) {
+	m := newGangManager()
+	pods := []*corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "ns"}},
+	}
+	for _, pod := range pods {
+		m.setPod("ns/pg", pod)
+	}
+	m.setWaiting("ns/pg", "pod1", time.Minute)
+	m.admit("ns/pg", "pod2")
+	if got, expected := m.counts("ns/pg"), (memberCounts{total: 2, bound: 2, assumed: 2}); got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	if got := m.waiting("ns/pg"); got != 0 {
+		t.Errorf("expected 0 waiting, got %v", got)
+	}
+
+	// An update before the binding is seen keeps the member assumed.
+	updated := pods[0].DeepCopy()
+	updated.Labels = map[string]string{"updated": "true"}
+	m.setPod("ns/pg", updated)
+	if got, expected := m.counts("ns/pg"), (memberCounts{total: 2, bound: 2, assumed: 2}); got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	// The binding is seen.
+	bound := updated.DeepCopy()
+	bound.Spec.NodeName = "node1"
+	m.setPod("ns/pg", bound)
+	if got, expected := m.counts("ns/pg"), (memberCounts{total: 2, bound: 2, assumed: 1}); got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	// The binding failed.
+	m.unreserve("ns/pg", "pod2")
+	if got, expected := m.counts("ns/pg"), (memberCounts{total: 2, pending: 1, bound: 1}); got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestGangManagerPodGroupCache(t *testing.T) {
+	m := newGangManager()
+	pg := &podGroup{namespace: "ns", name: "pg", minAvailable: 2}
+
+	_, cached, token, _ := m.cachedPodGroup("ns/pg")
+	if cached {
+		t.Fatalf("expected nothing cached")
+	}
+	m.cachePodGroup("ns/pg", token, pg, nil)
+	if got, cached, _, _ := m.cachedPodGroup("ns/pg"); !cached || got != pg {
+		t.Errorf("expected %+v cached, got %+v", pg, got)
+	}
+
+	m.invalidate("ns/pg")
+	if _, cached, _, _ := m.cachedPodGroup("ns/pg"); cached {
+		t.Errorf("expected the group to be invalidated")
+	}
+
+	// A lookup started before an invalidation of its group is not cached,
+	// one started before an invalidation of another group is.
+	_, _, token, _ = m.cachedPodGroup("ns/pg")
+	m.invalidate("ns/pg")
+	m.cachePodGroup("ns/pg", token, pg, nil)
+	if _, cached, _, _ := m.cachedPodGroup("ns/pg"); cached {
+		t.Errorf("expected a stale lookup not to be cached")
+	}
+	_, _, token, _ = m.cachedPodGroup("ns/pg")
+	m.invalidate("ns/other")
+	m.cachePodGroup("ns/pg", token, pg, nil)
+	if _, cached, _, _ := m.cachedPodGroup("ns/pg"); !cached {
+		t.Errorf("expected a lookup to be cached despite the invalidation of another group")
+	}
+
+	_, _, token, _ = m.cachedPodGroup("ns/missing")
+	m.cachePodGroup("ns/missing", token, nil, apierrors.NewNotFound(podGroupResource, "missing"))
+	if _, cached, _, err := m.cachedPodGroup("ns/missing"); !cached || !apierrors.IsNotFound(err) {
+		t.Errorf("expected not found to be cached, got %v", err)
+	}
+	m.invalidateAll()
+	if _, cached, _, _ := m.cachedPodGroup("ns/missing"); cached {
+		t.Errorf("expected every group to be invalidated")
+	}
+}
+
+func TestGroupObjectEventHandlers(t *testing.T) {
+	cm := func(data map[string]string, annotations map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "ns", Annotations: annotations}, Data: data}
+	}
+	group := map[string]string{minAvailable: "2"}
+	crd := func(generation int64) *v1alpha1.PodGroup {
+		return &v1alpha1.PodGroup{ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "ns", Generation: generation}}
+	}
+	for _, tt := range []struct {
+		name        string
+		event       func(s *Sample)
+		invalidated bool
+	}{
+		{
+			name:  "configmap that is not a group is added",
+			event: func(s *Sample) { s.configMapEventHandler().OnAdd(cm(nil, nil)) },
+		},
+		{
+			name: "status annotations of a group configmap are updated",
+			event: func(s *Sample) {
+				s.configMapEventHandler().OnUpdate(cm(group, nil), cm(group, map[string]string{v1alpha1.PhaseAnnotation: "Running"}))
+			},
+		},
+		{
+			name: "data of a group configmap is updated",
+			event: func(s *Sample) {
+				s.configMapEventHandler().OnUpdate(cm(group, nil), cm(map[string]string{minAvailable: "3"}, nil))
+			},
+			invalidated: true,
+		},
+		{
+			name:        "configmap stops being a group",
+			event:       func(s *Sample) { s.configMapEventHandler().OnUpdate(cm(group, nil), cm(nil, nil)) },
+			invalidated: true,
+		},
+		{
+			name:  "status of a podgroup is updated",
+			event: func(s *Sample) { s.groupObjectEventHandler(specChanged).OnUpdate(crd(1), crd(1)) },
+		},
+		{
+			name:        "spec of a podgroup is updated",
+			event:       func(s *Sample) { s.groupObjectEventHandler(specChanged).OnUpdate(crd(1), crd(2)) },
+			invalidated: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Sample{gangs: newGangManager()}
+			pg := &podGroup{namespace: "ns", name: "pg", minAvailable: 2}
+			_, _, token, _ := s.gangs.cachedPodGroup("ns/pg")
+			s.gangs.cachePodGroup("ns/pg", token, pg, nil)
+			tt.event(s)
+			if _, cached, _, _ := s.gangs.cachedPodGroup("ns/pg"); cached == tt.invalidated {
+				t.Errorf("expected invalidated %v, got %v", tt.invalidated, !cached)
+			}
+		})
+	}
+}
+
+func TestGangManagerConcurrentAccess(t *testing.T) {
+	m := newGangManager()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d-%d", i, j), Namespace: "ns"}}
+				m.setPod("ns/pg", pod)
+				m.setWaiting("ns/pg", pod.Name, time.Minute)
+				m.counts("ns/pg")
+				m.members("ns/pg")
+				m.invalidate("ns/pg")
+				m.admit("ns/pg", pod.Name)
+				m.unreserve("ns/pg", pod.Name)
+				m.deletePod(pod)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if got := m.counts("ns/pg"); got != (memberCounts{}) {
+		t.Errorf("expected no member left, got %+v", got)
+	}
+}
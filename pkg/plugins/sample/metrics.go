@@ -0,0 +1,83 @@
+package sample
+
+import (
+	"sync"
+
+	"k8s.io/component-base/metrics"
+	schedulermetrics "k8s.io/kubernetes/pkg/scheduler/metrics"
+)
+
+// Permit results of the members of groups.
+const (
+	permitAllow   = "allow"
+	permitWait    = "wait"
+	permitTimeout = "timeout"
+	permitError   = "error"
+)
+
+// The metrics are labeled by namespace at most, never by group, so that
+// their cardinality stays bounded however many groups come and go.
+var (
+	waitingPods = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      schedulermetrics.SchedulerSubsystem,
+			Name:           "gang_waiting_pods",
+			Help:           "Number of members of groups held in Permit, by namespace.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"namespace"})
+	waitingGroups = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      schedulermetrics.SchedulerSubsystem,
+			Name:           "gang_waiting_groups",
+			Help:           "Number of groups with members held in Permit, by namespace.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"namespace"})
+	admissionDuration = metrics.NewHistogram(
+		&metrics.HistogramOpts{
+			Subsystem:      schedulermetrics.SchedulerSubsystem,
+			Name:           "gang_admission_duration_seconds",
+			Help:           "Time from the first member of a group held in Permit to the admission of the group, in seconds.",
+			Buckets:        metrics.ExponentialBuckets(0.01, 2, 17),
+			StabilityLevel: metrics.ALPHA,
+		})
+	permitResults = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      schedulermetrics.SchedulerSubsystem,
+			Name:           "gang_permit_results_total",
+			Help:           "Number of Permit results of the members of groups, by result: allow, wait, timeout or error.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"result"})
+	groupBackoffs = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      schedulermetrics.SchedulerSubsystem,
+			Name:           "gang_group_backoffs_total",
+			Help:           "Number of times groups were backed off after they failed to assemble, by namespace.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"namespace"})
+	reclaimedGroups = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      schedulermetrics.SchedulerSubsystem,
+			Name:           "gang_reclaimed_groups_total",
+			Help:           "Number of groups, or pods not in a group, preempted to give back the min of a queue they borrowed, by namespace.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"namespace"})
+
+	metricsList = []metrics.Registerable{
+		waitingPods,
+		waitingGroups,
+		admissionDuration,
+		permitResults,
+		groupBackoffs,
+		reclaimedGroups,
+	}
+)
+
+var registerOnce sync.Once
+
+// registerMetrics registers the metrics of the plugin in the legacy registry,
+// so that they are served with the kube-scheduler metrics.
+func registerMetrics() {
+	registerOnce.Do(func() {
+		schedulermetrics.RegisterMetrics(metricsList...)
+	})
+}
@@ -0,0 +1,94 @@
+package sample
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	framework_rt "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+)
+
+func TestMetrics(t *testing.T) {
+	registerMetrics()
+	f, err := newFrameworkWithQueueSortAndBind(framework_rt.Registry{}, &config.Plugins{}, emptyArgs,
+		framework_rt.WithSnapshotSharedLister(&fakeSharedLister{}))
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+	s := newFakeSample(f)
+	member := func(name, group string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "metrics", Labels: map[string]string{PodGroupName: group}}}
+	}
+
+	gauge := func(m *metrics.GaugeVec) float64 {
+		v, err := testutil.GetGaugeMetricValue(m.WithLabelValues("metrics"))
+		if err != nil {
+			t.Fatalf("fail to read metric: %v", err)
+		}
+		return v
+	}
+	counter := func(m metrics.CounterMetric) float64 {
+		v, err := testutil.GetCounterMetricValue(m)
+		if err != nil {
+			t.Fatalf("fail to read metric: %v", err)
+		}
+		return v
+	}
+	histogramCount := func() uint64 {
+		h, err := testutil.GetHistogramFromGatherer(legacyregistry.DefaultGatherer, "scheduler_gang_admission_duration_seconds")
+		if err != nil {
+			t.Fatalf("fail to read metric: %v", err)
+		}
+		return h.GetSampleCount()
+	}
+	expect := func(what string, got, expected float64) {
+		t.Helper()
+		if got != expected {
+			t.Errorf("expected %v %v, got %v", expected, what, got)
+		}
+	}
+	allowed := counter(permitResults.WithLabelValues(permitAllow))
+	waited := counter(permitResults.WithLabelValues(permitWait))
+	timedOut := counter(permitResults.WithLabelValues(permitTimeout))
+	admissions := histogramCount()
+
+	for _, name := range []string{"pod1", "pod2"} {
+		if got, _ := s.Permit(context.TODO(), nil, member(name, "pg1"), ""); got.Code() != framework.Wait {
+			t.Fatalf("expected %v to wait, got %v", name, got.Code())
+		}
+	}
+	expect("waiting pods", gauge(waitingPods), 2)
+	expect("waiting groups", gauge(waitingGroups), 1)
+	expect("waits", counter(permitResults.WithLabelValues(permitWait))-waited, 2)
+
+	if got, _ := s.Permit(context.TODO(), nil, member("pod3", "pg1"), ""); got.Code() != framework.Success {
+		t.Fatalf("expected pod3 to be allowed, got %v", got.Code())
+	}
+	expect("waiting pods", gauge(waitingPods), 0)
+	expect("waiting groups", gauge(waitingGroups), 0)
+	expect("allows", counter(permitResults.WithLabelValues(permitAllow))-allowed, 1)
+	expect("admissions", float64(histogramCount()-admissions), 1)
+
+	// The wait of a member of another group times out, its sibling is
+	// rejected with it and the group backs off once.
+	timeouts := []*corev1.Pod{member("pod1", "pg2"), member("pod2", "pg2")}
+	for _, p := range timeouts {
+		if got, _ := s.Permit(context.TODO(), nil, p, ""); got.Code() != framework.Wait {
+			t.Fatalf("expected %v to wait, got %v", p.Name, got.Code())
+		}
+	}
+	backoffs := counter(groupBackoffs.WithLabelValues("metrics"))
+	for _, p := range timeouts {
+		s.Unreserve(context.TODO(), nil, p, "")
+	}
+	expect("waiting pods", gauge(waitingPods), 0)
+	expect("waiting groups", gauge(waitingGroups), 0)
+	expect("timeouts", counter(permitResults.WithLabelValues(permitTimeout))-timedOut, 1)
+	expect("backoffs", counter(groupBackoffs.WithLabelValues("metrics"))-backoffs, 1)
+}
@@ -0,0 +1,194 @@
+package sample
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+	schedulingapi "k8s.io/kubernetes/pkg/apis/scheduling"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+	pgclientset "github.com/FFFFFaraway/gang-scheduler/pkg/generated/clientset/versioned"
+)
+
+// podGroup is the scheduling view of a group, no matter which groupSource
+// declares it.
+type podGroup struct {
+	namespace    string
+	name         string
+	minAvailable int
+	// maxMember is the maximum number of members holding a node at the same
+	// time, 0 if there is none.
+	maxMember int
+	// minRoles are the minimum numbers of members of each role, nil if the
+	// group sets none.
+	minRoles map[string]int
+	// placement is how the members are placed across the topology domains,
+	// Pack when the group does not say.
+	placement v1alpha1.PodGroupPlacement
+	// requiredTopologyKeys are the node labels of the topology domains the
+	// members must be placed in a single one of, from the widest to the
+	// narrowest, nil if the group requires none.
+	requiredTopologyKeys []string
+	// timeoutSeconds is the scheduleTimeoutSeconds the group asks for, 0 if
+	// it asks for none.
+	timeoutSeconds  int64
+	scheduleTimeout time.Duration
+	creationTime    time.Time
+	// priorityClassName is the priority class of the whole group, if any.
+	priorityClassName string
+	// source is the name of the groupSource declaring the group, ref the
+	// object declaring it, which events about the group are recorded on.
+	source string
+	ref    *v1.ObjectReference
+}
+
+var podGroupResource = v1alpha1.Resource("podgroups")
+
+// getPodGroup returns the group namespace/name, cached by the gang manager.
+// The returned group must not be modified.
+func (s *Sample) getPodGroup(namespace, name string) (*podGroup, error) {
+	key := namespace + "/" + name
+	pg, cached, token, err := s.gangs.cachedPodGroup(key)
+	if cached {
+		return pg, err
+	}
+	pg, err = s.lookupPodGroup(namespace, name)
+	s.gangs.cachePodGroup(key, token, pg, err)
+	return pg, err
+}
+
+// lookupPodGroup looks the group up in every groupSource in order and returns
+// the first one found, so that a PodGroup wins over a legacy ConfigMap of the
+// same name and existing workloads keep running.
+func (s *Sample) lookupPodGroup(namespace, name string) (*podGroup, error) {
+	for _, src := range s.groupSources() {
+		pg, err := src.get(namespace, name)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if pg.minAvailable < 1 {
+			return nil, &invalidGroupError{
+				ref: pg.ref,
+				msg: fmt.Sprintf("minAvailable of podGroup %v/%v must be at least 1, got %d", namespace, name, pg.minAvailable),
+			}
+		}
+		if pg.maxMember != 0 && pg.maxMember < pg.minAvailable {
+			return nil, &invalidGroupError{
+				ref: pg.ref,
+				msg: fmt.Sprintf("maxMember of podGroup %v/%v must be at least minAvailable(%d), got %d", namespace, name, pg.minAvailable, pg.maxMember),
+			}
+		}
+		switch pg.placement {
+		case "":
+			pg.placement = v1alpha1.PodGroupPlacementPack
+		case v1alpha1.PodGroupPlacementPack, v1alpha1.PodGroupPlacementSpread:
+		default:
+			return nil, &invalidGroupError{
+				ref: pg.ref,
+				msg: fmt.Sprintf("placement of podGroup %v/%v must be %v or %v, got %q", namespace, name,
+					v1alpha1.PodGroupPlacementPack, v1alpha1.PodGroupPlacementSpread, pg.placement),
+			}
+		}
+		if msg := validateMinRoles(pg); msg != "" {
+			return nil, &invalidGroupError{ref: pg.ref, msg: msg}
+		}
+		if msg := validateRequiredTopologyKeys(pg); msg != "" {
+			return nil, &invalidGroupError{ref: pg.ref, msg: msg}
+		}
+		pg.source = src.name()
+		pg.scheduleTimeout = s.scheduleTimeout(pg.timeoutSeconds)
+		return pg, nil
+	}
+	return nil, apierrors.NewNotFound(podGroupResource, name)
+}
+
+// invalidGroupError is returned for a group that its source declares wrongly,
+// e.g. with a minAvailable that is not a positive integer. The members of the
+// group are unschedulable until the object declaring it is fixed.
+type invalidGroupError struct {
+	// ref is the object declaring the group.
+	ref *v1.ObjectReference
+	msg string
+}
+
+func (e *invalidGroupError) Error() string {
+	return e.msg
+}
+
+// asInvalidGroup returns the invalidGroupError of err, if any.
+func asInvalidGroup(err error) (*invalidGroupError, bool) {
+	var invalid *invalidGroupError
+	return invalid, errors.As(err, &invalid)
+}
+
+// priority resolves the priority class of the group the way the priority
+// admission plugin does for pods: the global default class is used when the
+// group names a class that does not exist. A group naming no class takes the
+// priority of its first member, so that a gang of high priority pods does not
+// sort below plain pods, and the global default class while none is known.
+// The priority of a group does not change as members come and go.
+func (s *Sample) priority(pg *podGroup) int32 {
+	if pg.priorityClassName != "" {
+		if pc, err := s.pcLister.Get(pg.priorityClassName); err == nil {
+			return pc.Value
+		}
+	} else if priority := s.gangs.priority(pg.namespace + "/" + pg.name); priority != nil {
+		return *priority
+	}
+	classes, err := s.pcLister.List(labels.Everything())
+	if err != nil {
+		return schedulingapi.DefaultPriorityWhenNoDefaultClassExists
+	}
+	var defaultClass *schedulingv1.PriorityClass
+	for _, pc := range classes {
+		if pc.GlobalDefault && (defaultClass == nil || pc.Value < defaultClass.Value) {
+			defaultClass = pc
+		}
+	}
+	if defaultClass == nil {
+		return schedulingapi.DefaultPriorityWhenNoDefaultClassExists
+	}
+	return defaultClass.Value
+}
+
+// scheduleTimeout returns how long the members of a group asking for seconds
+// wait in Permit: the default timeout when it asks for none, and never more
+// than the max timeout.
+func (s *Sample) scheduleTimeout(seconds int64) time.Duration {
+	if seconds <= 0 {
+		seconds = s.args.DefaultTimeoutSeconds
+	}
+	if seconds > s.args.MaxTimeoutSeconds {
+		seconds = s.args.MaxTimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// newPodGroupClient returns a PodGroup clientset, or nil if the PodGroup CRD
+// is not installed in the cluster, in which case only ConfigMaps are used to
+// declare groups.
+func newPodGroupClient(restConfig *rest.Config) (pgclientset.Interface, error) {
+	client, err := pgclientset.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	served, err := resourceServed(client.Discovery(), v1alpha1.SchemeGroupVersion.WithResource("podgroups"))
+	if err != nil {
+		return nil, err
+	}
+	if !served {
+		klog.Warningf("%v is not served by the apiserver, falling back to configmap podgroups", v1alpha1.SchemeGroupVersion)
+		return nil, nil
+	}
+	return client, nil
+}
@@ -0,0 +1,260 @@
+package sample
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clientv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+	pglisters "github.com/FFFFFaraway/gang-scheduler/pkg/generated/listers/scheduling/v1alpha1"
+)
+
+func newIndexer(objs ...interface{}) cache.Indexer {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, obj := range objs {
+		_ = indexer.Add(obj)
+	}
+	return indexer
+}
+
+func TestGetPodGroup(t *testing.T) {
+	timeout := int32(30)
+	tooLong := int32(3600)
+	four, one := int32(4), int32(1)
+	pgs := newIndexer(
+		&v1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "both", Namespace: "ns"},
+			Spec:       v1alpha1.PodGroupSpec{MinAvailable: 4, ScheduleTimeoutSeconds: &timeout},
+		},
+		&v1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "crd-only", Namespace: "ns"},
+			Spec:       v1alpha1.PodGroupSpec{MinAvailable: 2},
+		},
+		&v1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "too-long", Namespace: "ns"},
+			Spec:       v1alpha1.PodGroupSpec{MinAvailable: 2, ScheduleTimeoutSeconds: &tooLong},
+		},
+		&v1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "zero", Namespace: "ns"},
+		},
+		&v1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "elastic", Namespace: "ns"},
+			Spec:       v1alpha1.PodGroupSpec{MinAvailable: 2, MaxMember: &four},
+		},
+		&v1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "max-below-min", Namespace: "ns"},
+			Spec:       v1alpha1.PodGroupSpec{MinAvailable: 2, MaxMember: &one},
+		},
+		&v1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "roles", Namespace: "ns"},
+			Spec:       v1alpha1.PodGroupSpec{MinAvailable: 5, MinRoles: map[string]int32{"ps": 1, "worker": 4}},
+		},
+		&v1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "roles-above-min", Namespace: "ns"},
+			Spec:       v1alpha1.PodGroupSpec{MinAvailable: 4, MinRoles: map[string]int32{"ps": 1, "worker": 4}},
+		},
+		&v1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "zero-role", Namespace: "ns"},
+			Spec:       v1alpha1.PodGroupSpec{MinAvailable: 4, MinRoles: map[string]int32{"ps": 0}},
+		},
+		&v1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "spread", Namespace: "ns"},
+			Spec:       v1alpha1.PodGroupSpec{MinAvailable: 2, Placement: v1alpha1.PodGroupPlacementSpread},
+		},
+	)
+	cms := newIndexer(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "both", Namespace: "ns"},
+			Data:       map[string]string{minAvailable: "3"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "cm-only", Namespace: "ns"},
+			Data:       map[string]string{minAvailable: "3", scheduleTimeoutSeconds: "5"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "bad", Namespace: "ns"},
+			Data:       map[string]string{minAvailable: "three"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "elastic-cm", Namespace: "ns"},
+			Data:       map[string]string{minAvailable: "2", maxMember: "3"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "bad-max", Namespace: "ns"},
+			Data:       map[string]string{minAvailable: "2", maxMember: "many"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "roles-cm", Namespace: "ns"},
+			Data:       map[string]string{minAvailable: "6", minRoles: "ps=2, worker=4"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "bad-roles", Namespace: "ns"},
+			Data:       map[string]string{minAvailable: "2", minRoles: "ps:1"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "spread-cm", Namespace: "ns"},
+			Data:       map[string]string{minAvailable: "2", placement: "Spread"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "bad-placement", Namespace: "ns"},
+			Data:       map[string]string{minAvailable: "2", placement: "Scatter"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "topology-cm", Namespace: "ns"},
+			Data:       map[string]string{minAvailable: "2", requiredTopologyKeys: "topology.kubernetes.io/zone, example.com/rack"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "same-topology-key", Namespace: "ns"},
+			Data:       map[string]string{minAvailable: "2", requiredTopologyKeys: "example.com/rack,example.com/rack"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "bad-topology-key", Namespace: "ns"},
+			Data:       map[string]string{minAvailable: "2", requiredTopologyKeys: "example.com/a rack"},
+		},
+	)
+
+	for _, tt := range []struct {
+		name            string
+		noCRD           bool
+		group           string
+		minAvailable    int
+		maxMember       int
+		minRoles        map[string]int
+		placement       v1alpha1.PodGroupPlacement
+		topologyKeys    []string
+		scheduleTimeout time.Duration
+		notFound        bool
+		invalid         bool
+		wantErr         bool
+	}{
+		{name: "podgroup wins over configmap", group: "both", minAvailable: 4, scheduleTimeout: 30 * time.Second},
+		{name: "podgroup with default timeout", group: "crd-only", minAvailable: 2, scheduleTimeout: 10 * time.Second},
+		{name: "podgroup timeout capped by max timeout", group: "too-long", minAvailable: 2, scheduleTimeout: 600 * time.Second},
+		{name: "configmap fallback", group: "cm-only", minAvailable: 3, scheduleTimeout: 5 * time.Second},
+		{name: "configmap only when crd is not installed", noCRD: true, group: "both", minAvailable: 3, scheduleTimeout: 10 * time.Second},
+		{name: "invalid configmap", group: "bad", invalid: true, wantErr: true},
+		{name: "podgroup without minAvailable", group: "zero", invalid: true, wantErr: true},
+		{name: "elastic podgroup", group: "elastic", minAvailable: 2, maxMember: 4, scheduleTimeout: 10 * time.Second},
+		{name: "elastic configmap", group: "elastic-cm", minAvailable: 2, maxMember: 3, scheduleTimeout: 10 * time.Second},
+		{name: "maxMember below minAvailable", group: "max-below-min", invalid: true, wantErr: true},
+		{name: "invalid maxMember", group: "bad-max", invalid: true, wantErr: true},
+		{name: "podgroup with roles", group: "roles", minAvailable: 5, minRoles: map[string]int{"ps": 1, "worker": 4}, scheduleTimeout: 10 * time.Second},
+		{name: "configmap with roles", group: "roles-cm", minAvailable: 6, minRoles: map[string]int{"ps": 2, "worker": 4}, scheduleTimeout: 10 * time.Second},
+		{name: "roles above minAvailable", group: "roles-above-min", invalid: true, wantErr: true},
+		{name: "role without members", group: "zero-role", invalid: true, wantErr: true},
+		{name: "invalid minRoles", group: "bad-roles", invalid: true, wantErr: true},
+		{name: "spread podgroup", group: "spread", minAvailable: 2, placement: v1alpha1.PodGroupPlacementSpread, scheduleTimeout: 10 * time.Second},
+		{name: "spread configmap", group: "spread-cm", minAvailable: 2, placement: v1alpha1.PodGroupPlacementSpread, scheduleTimeout: 10 * time.Second},
+		{name: "invalid placement", group: "bad-placement", invalid: true, wantErr: true},
+		{name: "configmap with required topology", group: "topology-cm", minAvailable: 2,
+			topologyKeys: []string{"topology.kubernetes.io/zone", "example.com/rack"}, scheduleTimeout: 10 * time.Second},
+		{name: "topology key given twice", group: "same-topology-key", invalid: true, wantErr: true},
+		{name: "invalid topology key", group: "bad-topology-key", invalid: true, wantErr: true},
+		{name: "missing group", group: "missing", notFound: true, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Sample{args: defaultArgs(), gangs: newGangManager(), cmLister: clientv1.NewConfigMapLister(cms)}
+			if !tt.noCRD {
+				s.pgLister = pglisters.NewPodGroupLister(pgs)
+			}
+			pg, err := s.getPodGroup("ns", tt.group)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.notFound != apierrors.IsNotFound(err) {
+				t.Errorf("expected not found %v, got %v", tt.notFound, err)
+			}
+			if invalid, ok := asInvalidGroup(err); ok != tt.invalid {
+				t.Errorf("expected invalid %v, got %v", tt.invalid, err)
+			} else if ok && invalid.ref == nil {
+				t.Errorf("expected the object declaring the invalid group")
+			}
+			if err != nil {
+				return
+			}
+			if pg.minAvailable != tt.minAvailable {
+				t.Errorf("expected minAvailable %v, got %v", tt.minAvailable, pg.minAvailable)
+			}
+			if pg.maxMember != tt.maxMember {
+				t.Errorf("expected maxMember %v, got %v", tt.maxMember, pg.maxMember)
+			}
+			if diff := cmp.Diff(tt.minRoles, pg.minRoles); diff != "" {
+				t.Errorf("unexpected minRoles (-want,+got): %s", diff)
+			}
+			placement := tt.placement
+			if placement == "" {
+				placement = v1alpha1.PodGroupPlacementPack
+			}
+			if pg.placement != placement {
+				t.Errorf("expected placement %v, got %v", placement, pg.placement)
+			}
+			if diff := cmp.Diff(tt.topologyKeys, pg.requiredTopologyKeys); diff != "" {
+				t.Errorf("unexpected requiredTopologyKeys (-want,+got): %s", diff)
+			}
+			if pg.scheduleTimeout != tt.scheduleTimeout {
+				t.Errorf("expected scheduleTimeout %v, got %v", tt.scheduleTimeout, pg.scheduleTimeout)
+			}
+		})
+	}
+}
+
+func TestGroupName(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		expected    string
+	}{
+		{name: "no group", expected: ""},
+		{name: "own label", labels: map[string]string{PodGroupName: "own"}, expected: "own"},
+		{name: "coscheduling label", labels: map[string]string{"pod-group.scheduling.sigs.k8s.io": "cosched"}, expected: "cosched"},
+		{name: "volcano annotation", annotations: map[string]string{"scheduling.k8s.io/group-name": "volcano"}, expected: "volcano"},
+		{
+			name:        "own label wins",
+			labels:      map[string]string{PodGroupName: "own", "pod-group.scheduling.sigs.k8s.io": "cosched"},
+			annotations: map[string]string{"scheduling.k8s.io/group-name": "volcano"},
+			expected:    "own",
+		},
+		{
+			name:        "labels win over annotations",
+			labels:      map[string]string{"pod-group.scheduling.sigs.k8s.io": "cosched"},
+			annotations: map[string]string{"scheduling.k8s.io/group-name": "volcano"},
+			expected:    "cosched",
+		},
+		{name: "empty label is ignored", labels: map[string]string{PodGroupName: "", "pod-group.scheduling.sigs.k8s.io": "cosched"}, expected: "cosched"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Sample{args: defaultArgs()}
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: tt.labels, Annotations: tt.annotations}}
+			if got := s.groupName(pod); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGroupMembers(t *testing.T) {
+	pods := []*corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "own", Namespace: "ns", Labels: map[string]string{PodGroupName: "pg"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "cosched", Namespace: "ns", Labels: map[string]string{"pod-group.scheduling.sigs.k8s.io": "pg"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "volcano", Namespace: "ns", Annotations: map[string]string{"scheduling.k8s.io/group-name": "pg"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns", Labels: map[string]string{PodGroupName: "other", "pod-group.scheduling.sigs.k8s.io": "pg"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other-ns", Namespace: "other", Labels: map[string]string{PodGroupName: "pg"}}},
+	}
+	s := &Sample{args: defaultArgs(), gangs: newGangManager()}
+	addPods(s, pods...)
+	got := sets.NewString()
+	for _, p := range s.groupMembers("ns", "pg") {
+		got.Insert(p.Name)
+	}
+	if expected := sets.NewString("own", "cosched", "volcano"); !got.Equal(expected) {
+		t.Errorf("expected members %v, got %v", expected.List(), got.List())
+	}
+}
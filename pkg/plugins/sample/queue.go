@@ -0,0 +1,476 @@
+package sample
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/clock"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/client-go/tools/cache"
+	resourcehelper "k8s.io/kubernetes/pkg/api/v1/resource"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+	"github.com/FFFFFaraway/gang-scheduler/pkg/plugins/resources"
+)
+
+// queueManager keeps the resources used by the pods of every queue in
+// memory, fed by the pod informer and by Reserve, so that gangs are admitted
+// against the quota of their queue without listing pods. Queues are keyed by
+// name.
+type queueManager struct {
+	clock clock.PassiveClock
+
+	lock sync.RWMutex
+	// pods are the pods using resources, by pod key.
+	pods map[string]*queuePod
+	// used are the resources used by the pods of each queue.
+	used map[string]v1.ResourceList
+	// gangs are the gangs admitted in PreFilter that have not formed yet, by
+	// group key. Their members use resources as soon as the first one is
+	// admitted, so that two gangs of a queue do not both take a part of what
+	// is left and hold it in Permit waiting for the rest.
+	gangs map[string]*queueGang
+	// admitted are the group keys of the members of gangs, by pod key.
+	admitted map[string]string
+}
+
+// queuePod is a pod using resources of its queue.
+type queuePod struct {
+	queue    string
+	requests v1.ResourceList
+	// reserved pods are reserved a node by the scheduler, but the pod
+	// informer has not seen them bound yet.
+	reserved bool
+}
+
+// queueGang is a gang admitted against the quota of its queue, until it
+// reaches its quorum, is rolled back or its deadline passes.
+type queueGang struct {
+	queue string
+	// members are the requests of the members admitted, by pod key. The ones
+	// in pods are counted there instead.
+	members map[string]v1.ResourceList
+	// borrowed are the resources the gang was admitted with beyond the min of
+	// its queue.
+	borrowed []v1.ResourceName
+	deadline time.Time
+}
+
+func newQueueManager() *queueManager {
+	return &queueManager{
+		clock:    clock.RealClock{},
+		pods:     map[string]*queuePod{},
+		used:     map[string]v1.ResourceList{},
+		gangs:    map[string]*queueGang{},
+		admitted: map[string]string{},
+	}
+}
+
+// queueRequests returns the resources pod takes from the quota of its queue:
+// what it requests on a node, and the pod itself.
+func queueRequests(pod *v1.Pod) v1.ResourceList {
+	requests, _ := resourcehelper.PodRequestsAndLimits(pod)
+	requests[v1.ResourcePods] = *resource.NewQuantity(1, resource.DecimalSI)
+	return requests
+}
+
+// usesQuota returns whether pod uses the resources of its queue: it is bound
+// to a node and has not finished.
+func usesQuota(pod *v1.Pod) bool {
+	return pod.Spec.NodeName != "" && pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed
+}
+
+// setPod records pod as a pod of queue.
+func (m *queueManager) setPod(queue string, pod *v1.Pod) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := podKey(pod)
+	old, exist := m.pods[key]
+	if !usesQuota(pod) {
+		if exist && old.reserved && pod.Spec.NodeName == "" && pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed {
+			// Its binding is still in flight.
+			return
+		}
+		m.remove(key)
+		return
+	}
+	m.remove(key)
+	m.add(key, &queuePod{queue: queue, requests: queueRequests(pod)})
+}
+
+// reserve records that pod of queue is reserved a node, until the pod
+// informer sees it bound or unreserve is called.
+func (m *queueManager) reserve(queue string, pod *v1.Pod) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := podKey(pod)
+	if _, exist := m.pods[key]; exist {
+		return
+	}
+	m.add(key, &queuePod{queue: queue, requests: queueRequests(pod), reserved: true})
+}
+
+// unreserve forgets pod if it is only reserved a node.
+func (m *queueManager) unreserve(pod *v1.Pod) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if p, exist := m.pods[podKey(pod)]; exist && p.reserved {
+		m.remove(podKey(pod))
+	}
+}
+
+// deletePod forgets pod.
+func (m *queueManager) deletePod(pod *v1.Pod) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := podKey(pod)
+	m.remove(key)
+	if gang, exist := m.admitted[key]; exist {
+		if g, exist := m.gangs[gang]; exist {
+			delete(g.members, key)
+		}
+		delete(m.admitted, key)
+	}
+}
+
+// admit records that the gang of the group key is admitted with members
+// against the quota of queue, borrowing borrowed beyond its min, until
+// release is called or timeout passes. Admitting the gang again adds members
+// to it and pushes its deadline back.
+func (m *queueManager) admit(key, queue string, members []*v1.Pod, borrowed []v1.ResourceName, timeout time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	now := m.clock.Now()
+	for gang, g := range m.gangs {
+		if !now.Before(g.deadline) {
+			m.release(gang)
+		}
+	}
+	g, exist := m.gangs[key]
+	if !exist {
+		if len(members) == 0 {
+			return
+		}
+		g = &queueGang{queue: queue, members: map[string]v1.ResourceList{}}
+		m.gangs[key] = g
+	}
+	g.deadline = now.Add(timeout)
+	g.borrowed = unionResources(g.borrowed, borrowed)
+	for _, p := range members {
+		g.members[podKey(p)] = queueRequests(p)
+		m.admitted[podKey(p)] = key
+	}
+}
+
+// releaseGang forgets the gang of the group key, its members that do not
+// use resources yet stop counting.
+func (m *queueManager) releaseGang(key string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.release(key)
+}
+
+// release forgets the gang of the group key. The lock must be held.
+func (m *queueManager) release(key string) {
+	g, exist := m.gangs[key]
+	if !exist {
+		return
+	}
+	for member := range g.members {
+		// A member admitted with another gang since stays admitted with it.
+		if m.admitted[member] == key {
+			delete(m.admitted, member)
+		}
+	}
+	delete(m.gangs, key)
+}
+
+// live returns the gang of the group key while it is admitted. The lock
+// must be held.
+func (m *queueManager) live(key string) (*queueGang, bool) {
+	g, exist := m.gangs[key]
+	if !exist || !m.clock.Now().Before(g.deadline) {
+		return nil, false
+	}
+	return g, true
+}
+
+// add counts the pod of key. The lock must be held.
+func (m *queueManager) add(key string, p *queuePod) {
+	m.pods[key] = p
+	m.used[p.queue] = quotav1.Add(m.used[p.queue], p.requests)
+}
+
+// remove stops counting the pod of key. The lock must be held.
+func (m *queueManager) remove(key string) {
+	p, exist := m.pods[key]
+	if !exist {
+		return
+	}
+	delete(m.pods, key)
+	used := quotav1.RemoveZeros(quotav1.Subtract(m.used[p.queue], p.requests))
+	if len(used) == 0 {
+		delete(m.used, p.queue)
+		return
+	}
+	m.used[p.queue] = used
+}
+
+// borrowed returns the resources the gang of the group key was admitted
+// with beyond the min of its queue, nil unless it is admitted.
+func (m *queueManager) borrowed(key string) []v1.ResourceName {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if g, live := m.live(key); live {
+		return g.borrowed
+	}
+	return nil
+}
+
+// counted returns whether pod already uses the resources of its queue, or
+// is admitted with its gang.
+func (m *queueManager) counted(pod *v1.Pod) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	key := podKey(pod)
+	if _, exist := m.pods[key]; exist {
+		return true
+	}
+	_, admitted := m.live(m.admitted[key])
+	return admitted
+}
+
+// usedBy returns the resources used by the pods of queue, and by the members
+// of its admitted gangs.
+func (m *queueManager) usedBy(queue string) v1.ResourceList {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	used := m.used[queue].DeepCopy()
+	for key, g := range m.gangs {
+		if _, live := m.live(key); !live || g.queue != queue {
+			continue
+		}
+		for member, requests := range g.members {
+			if _, exist := m.pods[member]; !exist {
+				used = quotav1.Add(used, requests)
+			}
+		}
+	}
+	return used
+}
+
+// queueName returns the name of the queue of pod: its QueueLabelKey label,
+// or else its namespace.
+func (s *Sample) queueName(pod *v1.Pod) string {
+	if name := pod.Labels[s.args.QueueLabelKey]; name != "" {
+		return name
+	}
+	return pod.Namespace
+}
+
+// getQueue returns the Queue name, nil if there is none or the Queue CRD is
+// not installed.
+func (s *Sample) getQueue(name string) (*v1alpha1.Queue, error) {
+	if s.queueLister == nil {
+		return nil, nil
+	}
+	q, err := s.queueLister.Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return q, err
+}
+
+// quotaResult is the outcome of checking a gang against the quota of its
+// queue.
+type quotaResult struct {
+	// msg tells why the gang does not fit in its queue, empty if it does.
+	msg string
+	// borrowed are the resources the gang takes beyond the min of its queue,
+	// it may be preempted to give them back, see reclaim.
+	borrowed []v1.ResourceName
+	// members are the members of a gang that has not formed yet checked
+	// together, to be admitted with it.
+	members []*v1.Pod
+}
+
+// checkQuota tells whether the members of pg that do not use the resources
+// of their queue yet, pod included, fit under the max of the queue, and which
+// resources they borrow beyond its min. They may not borrow the resources
+// another queue is reclaiming. A pod not in a group is checked alone, pg is
+// nil then. Once a gang is formed, its extra members are checked one by one,
+// so that they are scheduled as they fit.
+func (s *Sample) checkQuota(pod *v1.Pod, pg *podGroup) (*quotaResult, error) {
+	queue := s.queueName(pod)
+	q, err := s.getQueue(queue)
+	if err != nil || q == nil {
+		return &quotaResult{}, err
+	}
+
+	if s.queues.counted(pod) {
+		// Retried while its binding is in flight.
+		return &quotaResult{}, nil
+	}
+	demand := queueRequests(pod)
+	what := fmt.Sprintf("pod %v/%v", pod.Namespace, pod.Name)
+	var members []*v1.Pod
+	if pg != nil && !s.formed(pg.namespace+"/"+pg.name, pg) {
+		what = fmt.Sprintf("podGroup %v/%v", pg.namespace, pg.name)
+		members = s.gangPending(pod, pg, queue)
+		demand = podsRequests(members)
+	}
+	used := s.queues.usedBy(queue)
+	var exceeded []string
+	for name, max := range q.Spec.Max {
+		asked, ok := demand[name]
+		if !ok || asked.IsZero() {
+			continue
+		}
+		total := used[name].DeepCopy()
+		total.Add(asked)
+		if total.Cmp(max) > 0 {
+			left := max.DeepCopy()
+			left.Sub(used[name])
+			if left.Sign() < 0 {
+				left = resource.Quantity{}
+			}
+			exceeded = append(exceeded, fmt.Sprintf("%v (asks for %v, %v of %v left)", name, asked.String(), left.String(), max.String()))
+		}
+	}
+	if len(exceeded) > 0 {
+		sort.Strings(exceeded)
+		return &quotaResult{
+			msg: fmt.Sprintf("%v does not fit in the max of queue %v: out of %v", what, queue, strings.Join(exceeded, ", ")),
+		}, nil
+	}
+
+	borrowed := beyondMin(used, demand, q)
+	for _, r := range s.pendingReclaims() {
+		if r.queue == queue {
+			continue
+		}
+		for _, name := range r.resources {
+			for _, b := range borrowed {
+				if b == name {
+					return &quotaResult{
+						msg: fmt.Sprintf("%v can not borrow %v beyond the min of queue %v while queue %v reclaims its min",
+							what, name, queue, r.queue),
+					}, nil
+				}
+			}
+		}
+	}
+	return &quotaResult{borrowed: borrowed, members: members}, nil
+}
+
+// unionResources returns the resources in a or b, sorted.
+func unionResources(a, b []v1.ResourceName) []v1.ResourceName {
+	seen := map[v1.ResourceName]bool{}
+	var union []v1.ResourceName
+	for _, name := range append(append([]v1.ResourceName{}, a...), b...) {
+		if !seen[name] {
+			seen[name] = true
+			union = append(union, name)
+		}
+	}
+	sort.Slice(union, func(i, j int) bool { return union[i] < union[j] })
+	return union
+}
+
+// beyondMin returns the resources named by q that demand asks for and that
+// take used beyond the min of q, sorted. A resource max names but min does
+// not is not guaranteed at all.
+func beyondMin(used, demand v1.ResourceList, q *v1alpha1.Queue) []v1.ResourceName {
+	var beyond []v1.ResourceName
+	for name, asked := range demand {
+		_, inMin := q.Spec.Min[name]
+		_, inMax := q.Spec.Max[name]
+		if asked.IsZero() || !inMin && !inMax {
+			continue
+		}
+		total := used[name].DeepCopy()
+		total.Add(asked)
+		if total.Cmp(q.Spec.Min[name]) > 0 {
+			beyond = append(beyond, name)
+		}
+	}
+	sort.Slice(beyond, func(i, j int) bool { return beyond[i] < beyond[j] })
+	return beyond
+}
+
+// gangDemand returns the resources the members of pg in queue that do not
+// use its resources yet, pod included, take from it once the gang is
+// admitted, see gangPending.
+func (s *Sample) gangDemand(pod *v1.Pod, pg *podGroup, queue string) v1.ResourceList {
+	return podsRequests(s.gangPending(pod, pg, queue))
+}
+
+// podsRequests returns the resources pods take from the quota of their queue.
+func podsRequests(pods []*v1.Pod) v1.ResourceList {
+	requests := v1.ResourceList{}
+	for _, p := range pods {
+		requests = quotav1.Add(requests, queueRequests(p))
+	}
+	return requests
+}
+
+// gangPending returns the members of pg in queue that do not use its
+// resources yet, pod included. An elastic group only takes up to maxMember
+// members, the biggest first.
+func (s *Sample) gangPending(pod *v1.Pod, pg *podGroup, queue string) []*v1.Pod {
+	seen := map[string]bool{}
+	var pending []*v1.Pod
+	for _, m := range append([]*v1.Pod{pod}, s.groupMembers(pg.namespace, pg.name)...) {
+		if seen[m.Name] || m.Spec.NodeName != "" || m.DeletionTimestamp != nil ||
+			m.Status.Phase == v1.PodSucceeded || m.Status.Phase == v1.PodFailed ||
+			s.queueName(m) != queue || s.queues.counted(m) {
+			continue
+		}
+		seen[m.Name] = true
+		pending = append(pending, m)
+	}
+	if pg.maxMember > 0 {
+		room := pg.maxMember - s.holding(pg.namespace+"/"+pg.name)
+		if room < 0 {
+			room = 0
+		}
+		if len(pending) > room {
+			sort.SliceStable(pending, func(i, j int) bool {
+				return resourceLess(resources.PodRequest(pending[j]), resources.PodRequest(pending[i]))
+			})
+			pending = pending[:room]
+		}
+	}
+	return pending
+}
+
+// queuePodEventHandler returns the handler keeping the resources used by the
+// pods of every queue.
+func (s *Sample) queuePodEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := obj.(*v1.Pod); ok {
+				s.queues.setPod(s.queueName(pod), pod)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if pod, ok := obj.(*v1.Pod); ok {
+				s.queues.setPod(s.queueName(pod), pod)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if pod, ok := obj.(*v1.Pod); ok {
+				s.queues.deletePod(pod)
+			}
+		},
+	}
+}
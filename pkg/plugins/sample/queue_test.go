@@ -0,0 +1,247 @@
+package sample
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	clientv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	framework_rt "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+	pglisters "github.com/FFFFFaraway/gang-scheduler/pkg/generated/listers/scheduling/v1alpha1"
+)
+
+func TestQueueManager(t *testing.T) {
+	oneGPU := corev1.ResourceList{gpu: resource.MustParse("1")}
+	m := newQueueManager()
+	bound := gangMember("bound", oneGPU)
+	bound.Spec.NodeName = "node1"
+	reserved := gangMember("reserved", oneGPU)
+	m.setPod("q", bound)
+	m.setPod("q", gangMember("pending", oneGPU))
+	m.reserve("q", reserved)
+
+	expectGPUs := func(want int64) {
+		t.Helper()
+		used := m.usedBy("q")
+		if got := used[gpu]; got.Value() != want {
+			t.Errorf("expected %d gpus used, got %v", want, used)
+		}
+	}
+	expectGPUs(2)
+
+	// Seen pending before its binding, it is still reserved.
+	m.setPod("q", reserved)
+	expectGPUs(2)
+	reserved = reserved.DeepCopy()
+	reserved.Spec.NodeName = "node1"
+	m.setPod("q", reserved)
+	m.unreserve(reserved)
+	expectGPUs(2)
+
+	finished := bound.DeepCopy()
+	finished.Status.Phase = corev1.PodSucceeded
+	m.setPod("q", finished)
+	expectGPUs(1)
+	m.deletePod(reserved)
+	if used := m.usedBy("q"); len(used) != 0 {
+		t.Errorf("expected nothing used, got %v", used)
+	}
+
+	// The members of an admitted gang count until it is released, or until
+	// its deadline passes.
+	fakeClock := clock.NewFakeClock(time.Now())
+	m.clock = fakeClock
+	admitted := []*corev1.Pod{gangMember("admitted-0", oneGPU), gangMember("admitted-1", oneGPU)}
+	m.admit("ns/pg", "q", admitted, nil, time.Minute)
+	expectGPUs(2)
+	m.reserve("q", admitted[0])
+	expectGPUs(2)
+	if !m.counted(admitted[1]) {
+		t.Errorf("expected the admitted member to be counted")
+	}
+	m.releaseGang("ns/pg")
+	expectGPUs(1)
+	m.admit("ns/pg", "q", admitted[1:], nil, time.Minute)
+	fakeClock.Step(time.Minute)
+	expectGPUs(1)
+	if m.counted(admitted[1]) {
+		t.Errorf("expected the member of an expired gang not to be counted")
+	}
+
+	// A member moved to another gang stays admitted with it.
+	moved := gangMember("moved", oneGPU)
+	m.admit("ns/pg", "q", []*corev1.Pod{moved}, nil, time.Minute)
+	m.admit("ns/other", "q", []*corev1.Pod{moved}, nil, time.Minute)
+	m.releaseGang("ns/pg")
+	if !m.counted(moved) {
+		t.Errorf("expected the member moved to another gang to be counted")
+	}
+	// The deletion of a member admitted with a gang that is gone is not
+	// fatal.
+	m.releaseGang("ns/other")
+	m.admitted[podKey(moved)] = "ns/other"
+	m.deletePod(moved)
+	if m.counted(moved) {
+		t.Errorf("expected the deleted member not to be counted")
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	twoGPUs := corev1.ResourceList{gpu: resource.MustParse("2"), corev1.ResourceCPU: resource.MustParse("1")}
+	queues := newIndexer(
+		&v1alpha1.Queue{ObjectMeta: metav1.ObjectMeta{Name: "ns"}, Spec: v1alpha1.QueueSpec{
+			Max: corev1.ResourceList{gpu: resource.MustParse("8")},
+		}},
+		&v1alpha1.Queue{ObjectMeta: metav1.ObjectMeta{Name: "team"}, Spec: v1alpha1.QueueSpec{
+			Max: corev1.ResourceList{gpu: resource.MustParse("4"), corev1.ResourceCPU: resource.MustParse("1")},
+		}},
+	)
+	for _, tt := range []struct {
+		name      string
+		members   int
+		maxMember string
+		// used are the gpus already used in the queue of the namespace.
+		used     int
+		queue    string
+		expected string
+	}{
+		{name: "gang fits", members: 3, used: 2},
+		{name: "gang exceeds the max", members: 3, used: 4,
+			expected: "podGroup ns/pg does not fit in the max of queue ns: out of nvidia.com/gpu (asks for 6, 4 of 8 left)"},
+		{name: "elastic gang counts up to maxMember", members: 4, maxMember: "2", used: 4},
+		{name: "queue named by the label", members: 2, queue: "team",
+			expected: "podGroup ns/pg does not fit in the max of queue team: out of cpu (asks for 2, 1 of 1 left)"},
+		{name: "queue without a Queue is not limited", members: 8, queue: "other"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]string{minAvailable: "2"}
+			if tt.maxMember != "" {
+				data[maxMember] = tt.maxMember
+			}
+			s := &Sample{args: defaultArgs(), gangs: newGangManager(), queues: newQueueManager(), reclaims: newReclaimCache(),
+				queueLister: pglisters.NewQueueLister(queues),
+				cmLister: clientv1.NewConfigMapLister(newIndexer(
+					&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "ns"}, Data: data},
+				))}
+			var members []*corev1.Pod
+			for i := 0; i < tt.members; i++ {
+				m := gangMember("member-"+string(rune('a'+i)), twoGPUs)
+				if tt.queue != "" {
+					m.Labels[s.args.QueueLabelKey] = tt.queue
+				}
+				members = append(members, m)
+			}
+			addPods(s, members...)
+			for i := 0; i < tt.used; i++ {
+				other := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other-" + string(rune('a'+i)), Namespace: "ns"},
+					Spec: corev1.PodSpec{NodeName: "node1", Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{gpu: resource.MustParse("1")}}}}}}
+				s.queues.setPod(s.queueName(other), other)
+			}
+			pg, err := s.getPodGroup("ns", "pg")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := s.checkQuota(members[0], pg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.msg != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got.msg)
+			}
+		})
+	}
+}
+
+func TestPreFilterQuota(t *testing.T) {
+	s := &Sample{args: defaultArgs(), gangs: newGangManager(), queues: newQueueManager(), reclaims: newReclaimCache(),
+		queueLister: pglisters.NewQueueLister(newIndexer(&v1alpha1.Queue{ObjectMeta: metav1.ObjectMeta{Name: "ns"},
+			Spec: v1alpha1.QueueSpec{Max: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("1")}}})),
+		cmLister: clientv1.NewConfigMapLister(newIndexer()),
+		backoff:  newGroupBackoff(2*time.Second, 2*time.Minute),
+	}
+	first := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "first", Namespace: "ns"}}
+	second := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "second", Namespace: "ns"}}
+	if status := s.PreFilter(context.TODO(), framework.NewCycleState(), first); !status.IsSuccess() {
+		t.Fatalf("expected the first pod to fit, got %v", status)
+	}
+	s.Reserve(context.TODO(), nil, first, "node1")
+	status := s.PreFilter(context.TODO(), framework.NewCycleState(), second)
+	if status.Code() != framework.UnschedulableAndUnresolvable || !strings.Contains(status.Message(), "out of pods") {
+		t.Errorf("expected the second pod to be out of pods, got %v", status)
+	}
+	s.queues.unreserve(first)
+	if status := s.PreFilter(context.TODO(), framework.NewCycleState(), second); !status.IsSuccess() {
+		t.Errorf("expected the second pod to fit once the first is unreserved, got %v", status)
+	}
+
+	// A pod whose queue does not exist has no quota to take.
+	other := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "other"}}
+	s.Reserve(context.TODO(), nil, other, "node1")
+	if s.queues.counted(other) {
+		t.Errorf("expected the pod of a queue that does not exist not to be counted")
+	}
+}
+
+func TestPreFilterGangQuota(t *testing.T) {
+	twoGPUs := corev1.ResourceList{gpu: resource.MustParse("2")}
+	member := func(group, name string) *corev1.Pod {
+		p := gangMember(name, twoGPUs)
+		p.Labels[PodGroupName] = group
+		return p
+	}
+	// Each gang asks for 4 of the 4 gpus of the queue, there is room for both
+	// on the node.
+	a0, a1, b0 := member("a", "a-0"), member("a", "a-1"), member("b", "b-0")
+	var s *Sample
+	registry := framework_rt.Registry{}
+	if err := registry.Register(Name, func(_ runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+		s = newFakeSample(handle)
+		s.cmLister = clientv1.NewConfigMapLister(newIndexer(
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "ns"}, Data: map[string]string{minAvailable: "2"}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "ns"}, Data: map[string]string{minAvailable: "2"}},
+		))
+		s.queueLister = pglisters.NewQueueLister(newIndexer(&v1alpha1.Queue{ObjectMeta: metav1.ObjectMeta{Name: "ns"},
+			Spec: v1alpha1.QueueSpec{Max: corev1.ResourceList{gpu: resource.MustParse("4")}}}))
+		addPods(s, a0, a1, b0, member("b", "b-1"))
+		return s, nil
+	}); err != nil {
+		t.Fatalf("fail to register prefilter plugin (%s)", Name)
+	}
+	cfgPls := &config.Plugins{PreFilter: config.PluginSet{Enabled: []config.Plugin{{Name: Name}}}}
+	if _, err := newFrameworkWithQueueSortAndBind(registry, cfgPls, emptyArgs,
+		framework_rt.WithSnapshotSharedLister(&fakeSharedLister{nodeInfos: []*framework.NodeInfo{gpuNode("node1", "8")}})); err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+
+	expectFits := func(pod *corev1.Pod, fits bool) {
+		t.Helper()
+		status := s.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+		if fits != status.IsSuccess() {
+			t.Errorf("expected %v to fit %v, got %v", pod.Name, fits, status)
+		}
+	}
+	// The first member of a admits the whole gang, b does not fit anymore
+	// while a is still gathering its quorum.
+	expectFits(a0, true)
+	expectFits(b0, false)
+	expectFits(a1, true)
+	expectFits(b0, false)
+
+	// a times out in Permit, what it was admitted with goes back to the queue
+	// and b takes it.
+	s.gangs.setWaiting("ns/a", a0.Name, time.Minute)
+	s.Unreserve(context.TODO(), nil, a0, "node1")
+	expectFits(b0, true)
+	expectFits(a0, false)
+}
@@ -0,0 +1,415 @@
+package sample
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+)
+
+const (
+	// reclaimTTL is how long other queues may not borrow what a gang
+	// reclaimed, long enough for the preempted pods to terminate and the gang
+	// to form.
+	reclaimTTL = 2 * time.Minute
+	// borrowedStateKey is the key in the cycle state of what the pod borrows.
+	borrowedStateKey framework.StateKey = Name + "/borrowed"
+)
+
+// borrowedState are the resources a pod, or its gang, was admitted with
+// beyond the min of its queue in PreFilter.
+type borrowedState struct {
+	resources []v1.ResourceName
+}
+
+func (b *borrowedState) Clone() framework.StateData {
+	return b
+}
+
+// markBorrowed records on pod the resources it, or its gang, borrowed beyond
+// the min of its queue when it was admitted, see BorrowedAnnotation. The mark
+// stays for the life of the pod, it only tells why the pod was let in: the
+// victims of a reclaim are chosen by what their queue uses now, see borrowers.
+func (s *Sample) markBorrowed(ctx context.Context, state *framework.CycleState, pod *v1.Pod) {
+	data, err := state.Read(borrowedStateKey)
+	if err != nil || len(data.(*borrowedState).resources) == 0 {
+		return
+	}
+	var names []string
+	for _, name := range data.(*borrowedState).resources {
+		names = append(names, string(name))
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": map[string]string{v1alpha1.BorrowedAnnotation: strings.Join(names, ",")}},
+	})
+	if err != nil {
+		klog.Errorf("failed to mark %v/%v as borrowing: %v", pod.Namespace, pod.Name, err)
+		return
+	}
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		_, err := s.handle.ClientSet().CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	}); err != nil {
+		klog.Errorf("failed to mark %v/%v as borrowing: %v", pod.Namespace, pod.Name, err)
+	}
+}
+
+// reclaim is the min of a queue that a gang of it takes back from the queues
+// borrowing it.
+type reclaim struct {
+	queue     string
+	resources []v1.ResourceName
+}
+
+// newReclaimCache returns the cache of the pending reclaims, keyed by the
+// namespace/group of the gang reclaiming.
+func newReclaimCache() *utilcache.LRUExpireCache {
+	return utilcache.NewLRUExpireCache(1024)
+}
+
+// pendingReclaims returns the reclaims that have not expired.
+func (s *Sample) pendingReclaims() []*reclaim {
+	var reclaims []*reclaim
+	for _, key := range s.reclaims.Keys() {
+		if r, ok := s.reclaims.Get(key); ok {
+			reclaims = append(reclaims, r.(*reclaim))
+		}
+	}
+	return reclaims
+}
+
+// borrower is a gang, or a pod not in a group, holding nodes beyond the min
+// of its queue.
+type borrower struct {
+	// key is the namespace/group of the gang, or the namespace/name of a pod
+	// not in a group.
+	key     string
+	queue   string
+	created time.Time
+	// pg is nil for a pod not in a group.
+	pg   *podGroup
+	pods []*v1.Pod
+}
+
+// guarantee returns the resources named by the min of the queue of pg that
+// the gang of pod asks for, nil unless the gang fits in that min.
+func (s *Sample) guarantee(pod *v1.Pod, pg *podGroup) (string, []v1.ResourceName, error) {
+	queue := s.queueName(pod)
+	q, err := s.getQueue(queue)
+	if err != nil || q == nil || len(q.Spec.Min) == 0 {
+		return queue, nil, err
+	}
+	demand := s.gangDemand(pod, pg, queue)
+	if len(beyondMin(s.queues.usedBy(queue), demand, q)) > 0 {
+		return queue, nil, nil
+	}
+	var resources []v1.ResourceName
+	for name := range q.Spec.Min {
+		if asked := demand[name]; !asked.IsZero() {
+			resources = append(resources, name)
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i] < resources[j] })
+	return queue, resources, nil
+}
+
+// borrowers returns the gangs of the queues other than queue that hold
+// nodes of nodeInfos and use one of resources their queue uses beyond its min
+// now, the newest first. The gangs of a queue are borrowers, the newest
+// first, until they add up to what the queue uses beyond its min, so that
+// preempting them all does not take the queue under its min. Pods in no
+// Queue are never borrowers.
+func (s *Sample) borrowers(nodeInfos []*framework.NodeInfo, queue string, resources []v1.ResourceName) []*borrower {
+	gangs := map[string]*borrower{}
+	queues := map[string]*v1alpha1.Queue{}
+	for _, ni := range nodeInfos {
+		for _, pi := range ni.Pods {
+			p := pi.Pod
+			if p.DeletionTimestamp != nil || p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed {
+				continue
+			}
+			name := s.queueName(p)
+			if name == queue {
+				continue
+			}
+			q, seen := queues[name]
+			if !seen {
+				q, _ = s.getQueue(name)
+				queues[name] = q
+			}
+			if q == nil {
+				continue
+			}
+			key := podKey(p)
+			var pg *podGroup
+			created := p.CreationTimestamp.Time
+			if group := s.groupName(p); group != "" {
+				key = p.Namespace + "/" + group
+				if found, err := s.getPodGroup(p.Namespace, group); err == nil {
+					pg, created = found, found.creationTime
+				}
+			}
+			b, exist := gangs[key]
+			if !exist {
+				b = &borrower{key: key, queue: name, created: created, pg: pg}
+				gangs[key] = b
+			}
+			if pg == nil && created.Before(b.created) {
+				b.created = created
+			}
+			b.pods = append(b.pods, p)
+		}
+	}
+
+	all := make([]*borrower, 0, len(gangs))
+	for _, b := range gangs {
+		all = append(all, b)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].created.Equal(all[j].created) {
+			return all[i].created.After(all[j].created)
+		}
+		return all[i].key > all[j].key
+	})
+	// over is what each queue uses beyond its min of resources, less the
+	// borrowers of the queue found so far.
+	over := map[string]v1.ResourceList{}
+	for name, q := range queues {
+		if q == nil {
+			continue
+		}
+		used := s.queues.usedBy(name)
+		beyond := v1.ResourceList{}
+		for _, r := range beyondMin(v1.ResourceList{}, used, q) {
+			for _, asked := range resources {
+				if r == asked {
+					quantity := used[r].DeepCopy()
+					quantity.Sub(q.Spec.Min[r])
+					beyond[r] = quantity
+				}
+			}
+		}
+		over[name] = beyond
+	}
+	var borrowers []*borrower
+	for _, b := range all {
+		beyond, requests := over[b.queue], podsRequests(b.pods)
+		borrowing := false
+		for r, quantity := range beyond {
+			if asked := requests[r]; quantity.Sign() > 0 && !asked.IsZero() {
+				borrowing = true
+			}
+		}
+		if !borrowing {
+			continue
+		}
+		for r, quantity := range beyond {
+			left := quantity.DeepCopy()
+			left.Sub(requests[r])
+			beyond[r] = left
+		}
+		borrowers = append(borrowers, b)
+	}
+	return borrowers
+}
+
+// reclaimVictims returns the borrowers to preempt, the newest first, so that
+// the members of pg still needing a node fit in the cluster, the node to
+// nominate pod to once they are gone, and whether the members would fit.
+// Only the nodes pod passes the Filter plugins on once every borrower is gone
+// are looked at, in the topology domain placeInDomain chooses if pg requires
+// one, and only the borrowers holding one of them are preempted. Pods already
+// terminating are taken as gone, no victim is needed when they make room,
+// e.g. the victims of a previous cycle. The members of pg held in Permit are
+// taken as pending, as PostFilter rejects them.
+func (s *Sample) reclaimVictims(ctx context.Context, state *framework.CycleState, nodeInfos []*framework.NodeInfo, pod *v1.Pod, pg *podGroup, borrowers []*borrower) ([]*borrower, string, bool) {
+	// filtered tells whether pod passes the Filter plugins on ni, once the
+	// removed pods are gone from it.
+	filtered := func(ni *framework.NodeInfo, removed []*v1.Pod) bool {
+		nodeState := state.Clone()
+		for _, p := range removed {
+			if status := s.handle.RunPreFilterExtensionRemovePod(ctx, nodeState, pod, framework.NewPodInfo(p), ni); !status.IsSuccess() {
+				klog.V(4).Infof("failed to remove %v/%v from the state of %v/%v: %v", p.Namespace, p.Name, pod.Namespace, pod.Name, status.Message())
+				return false
+			}
+		}
+		return s.handle.RunFilterPluginsWithNominatedPods(ctx, nodeState, pod, ni).IsSuccess()
+	}
+
+	borrowed := map[string][]*v1.Pod{}
+	for _, b := range borrowers {
+		for _, p := range b.pods {
+			borrowed[p.Spec.NodeName] = append(borrowed[p.Spec.NodeName], p)
+		}
+	}
+	// nodes are the nodes as the gang finds them, without the pods in gone,
+	// upper the same ones once every borrower is gone too.
+	var nodes, upper []*framework.NodeInfo
+	gone := map[string][]*v1.Pod{}
+	for _, ni := range nodeInfos {
+		if ni.Node() == nil {
+			continue
+		}
+		name, clone := ni.Node().Name, ni.Clone()
+		for _, pi := range ni.Pods {
+			p := pi.Pod
+			if p.DeletionTimestamp != nil ||
+				p.Namespace == pg.namespace && s.groupName(p) == pg.name && s.handle.GetWaitingPod(p.UID) != nil {
+				_ = clone.RemovePod(p)
+				gone[name] = append(gone[name], p)
+			}
+		}
+		nodes = append(nodes, clone)
+		freed := clone.Clone()
+		for _, p := range borrowed[name] {
+			_ = freed.RemovePod(p)
+		}
+		upper = append(upper, freed)
+	}
+	if len(pg.requiredTopologyKeys) > 0 {
+		if !s.placeInDomain(upper, pod, pg).fits {
+			return nil, "", false
+		}
+		upper = inDomain(upper, s.gangs.domain(pg.namespace+"/"+pg.name))
+	}
+	feasible := sets.NewString()
+	for _, ni := range upper {
+		name := ni.Node().Name
+		if filtered(ni, append(append([]*v1.Pod{}, gone[name]...), borrowed[name]...)) {
+			feasible.Insert(name)
+		}
+	}
+
+	byName := map[string]*framework.NodeInfo{}
+	var candidates []*framework.NodeInfo
+	for _, ni := range nodes {
+		if feasible.Has(ni.Node().Name) {
+			candidates = append(candidates, ni)
+			byName[ni.Node().Name] = ni
+		}
+	}
+	members := s.groupMembers(pg.namespace, pg.name)
+	fits := func() bool {
+		return simulateGang(candidates, pod, pg, members, s.countSucceeded(), s.args.RoleLabelKey).fits
+	}
+	if fits() {
+		return nil, "", true
+	}
+	var victims []*borrower
+	preempted := map[string][]*v1.Pod{}
+	for _, b := range borrowers {
+		held := false
+		for _, p := range b.pods {
+			if ni := byName[p.Spec.NodeName]; ni != nil {
+				_ = ni.RemovePod(p)
+				preempted[p.Spec.NodeName] = append(preempted[p.Spec.NodeName], p)
+				held = true
+			}
+		}
+		if !held {
+			continue
+		}
+		victims = append(victims, b)
+		if !fits() {
+			continue
+		}
+		// pod is nominated to the first node the victims free that it
+		// passes the filters on, if any.
+		names := make([]string, 0, len(preempted))
+		for name := range preempted {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if filtered(byName[name], append(append([]*v1.Pod{}, gone[name]...), preempted[name]...)) {
+				return victims, name, true
+			}
+		}
+		return victims, "", true
+	}
+	return nil, "", false
+}
+
+// reclaim preempts the gangs of other queues borrowing the min of the queue
+// of pg, the newest first, when the gang of pod fits in that min but not in
+// the cluster. The victims are preempted the way the default preemption does:
+// told why with a QuotaReclaimed event and deleted with their own grace
+// period, and the pods of lower priority nominated to the node pod is
+// nominated to lose their nomination. The resources reclaimed are not lent
+// again for reclaimTTL. It returns what it did, empty if it did nothing, and
+// the node pod is nominated to.
+func (s *Sample) reclaim(ctx context.Context, state *framework.CycleState, pod *v1.Pod, pg *podGroup) (string, string) {
+	key := pg.namespace + "/" + pg.name
+	if s.backoff.remaining(key) > 0 {
+		return "", ""
+	}
+	queue, resources, err := s.guarantee(pod, pg)
+	if err != nil || len(resources) == 0 {
+		return "", ""
+	}
+	nodeInfos, err := s.handle.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		return "", ""
+	}
+	victims, nominated, fits := s.reclaimVictims(ctx, state, nodeInfos, pod, pg, s.borrowers(nodeInfos, queue, resources))
+	if !fits || len(victims) == 0 {
+		return "", ""
+	}
+	s.reclaims.Add(key, &reclaim{queue: queue, resources: resources}, reclaimTTL)
+
+	cs := s.handle.ClientSet()
+	names := make([]string, 0, len(victims))
+	for _, v := range victims {
+		names = append(names, v.key)
+		msg := fmt.Sprintf("preempted to give the min of queue %v back to podGroup %v, it borrowed it from queue %v", queue, key, v.queue)
+		for _, p := range v.pods {
+			s.events.podEvent(p, v1.EventTypeWarning, reasonReclaimed, msg)
+			if waitingPod := s.handle.GetWaitingPod(p.UID); waitingPod != nil {
+				waitingPod.Reject(s.Name(), msg)
+				continue
+			}
+			if err := cs.CoreV1().Pods(p.Namespace).Delete(ctx, p.Name, metav1.DeleteOptions{
+				GracePeriodSeconds: p.Spec.TerminationGracePeriodSeconds,
+			}); err != nil && !apierrors.IsNotFound(err) {
+				klog.Errorf("failed to preempt %v/%v: %v", p.Namespace, p.Name, err)
+			}
+		}
+		s.events.groupEvent(v.pg, v1.EventTypeWarning, reasonReclaimed, msg)
+		namespace, _, _ := cache.SplitMetaNamespaceKey(v.key)
+		reclaimedGroups.WithLabelValues(namespace).Inc()
+	}
+	if nominated != "" {
+		// The pods of lower priority nominated to the node may not fit there
+		// anymore, they are sent to find another one.
+		var lower []*v1.Pod
+		for _, pi := range s.handle.NominatedPodsForNode(nominated) {
+			if corev1helpers.PodPriority(pi.Pod) < corev1helpers.PodPriority(pod) {
+				lower = append(lower, pi.Pod)
+			}
+		}
+		if err := util.ClearNominatedNodeName(cs, lower...); err != nil {
+			klog.Errorf("failed to clear the nominated node of the pods nominated to %v: %v", nominated, err)
+		}
+	}
+	msg := fmt.Sprintf("podGroup %v reclaims the min of queue %v by preempting %v", key, queue, strings.Join(names, ", "))
+	klog.V(3).Info(msg)
+	s.events.groupEvent(pg, v1.EventTypeNormal, reasonReclaiming, msg)
+	return msg, nominated
+}
@@ -0,0 +1,282 @@
+package sample
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	clientv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	framework_rt "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+	pglisters "github.com/FFFFFaraway/gang-scheduler/pkg/generated/listers/scheduling/v1alpha1"
+)
+
+func TestReclaim(t *testing.T) {
+	oneGPU := corev1.ResourceList{gpu: resource.MustParse("1")}
+	created := time.Now().Add(-time.Hour)
+	group := func(namespace, name string, age time.Duration) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace,
+			CreationTimestamp: metav1.NewTime(created.Add(-age))}, Data: map[string]string{minAvailable: "2"}}
+	}
+	member := func(namespace, group, name, nodeName string) *corev1.Pod {
+		p := gangMember(name, oneGPU)
+		p.Namespace, p.UID, p.Spec.NodeName = namespace, types.UID(namespace+"/"+name), nodeName
+		p.Labels[PodGroupName] = group
+		gracePeriod := int64(30)
+		p.Spec.TerminationGracePeriodSeconds = &gracePeriod
+		return p
+	}
+	queue := func(name, min string) *v1alpha1.Queue {
+		return &v1alpha1.Queue{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: v1alpha1.QueueSpec{
+			Min: corev1.ResourceList{gpu: resource.MustParse(min)},
+			Max: corev1.ResourceList{gpu: resource.MustParse("8")},
+		}}
+	}
+	// The borrower queue holds the 4 gpus guaranteed to the lender with two
+	// gangs, one on each node of its own zone. The gangs it uses beyond its
+	// min are preempted, the newer first.
+	running := []*corev1.Pod{
+		member("borrower", "old", "old-0", "node1"), member("borrower", "old", "old-1", "node1"),
+		member("borrower", "new", "new-0", "node2"), member("borrower", "new", "new-1", "node2"),
+	}
+	pending := []*corev1.Pod{member("lender", "pg", "pg-0", ""), member("lender", "pg", "pg-1", "")}
+	lenderPriority := int32(10)
+	for _, p := range pending {
+		p.Spec.Priority = &lenderPriority
+	}
+	// Pods of lower priority nominated to each node.
+	nominated := []*corev1.Pod{member("other", "low", "low-0", ""), member("other", "low", "low-1", "")}
+	nominated[0].Status.NominatedNodeName, nominated[1].Status.NominatedNodeName = "node1", "node2"
+
+	for _, tt := range []struct {
+		name        string
+		lenderMin   string
+		borrowerMin string
+		// marked are the gangs marked as borrowing when they were admitted.
+		marked []string
+		// rejected are the nodes a Filter plugin rejects the lender from.
+		rejected []string
+		// zone is set when the lender requires a single zone.
+		zone     bool
+		expected []string
+		// nominated is the node the lender is nominated to.
+		nominated string
+	}{
+		{name: "the newest borrowing gang is preempted", lenderMin: "4", borrowerMin: "0",
+			expected: []string{"old-0", "old-1"}, nominated: "node2"},
+		{name: "gangs marked as borrowing within the min of their queue are not preempted", lenderMin: "4", borrowerMin: "4",
+			marked: []string{"old", "new"}, expected: []string{"new-0", "new-1", "old-0", "old-1"}},
+		{name: "only the gangs beyond the min of their queue are preempted", lenderMin: "4", borrowerMin: "2",
+			marked: []string{"old", "new"}, rejected: []string{"node2"}, expected: []string{"new-0", "new-1", "old-0", "old-1"}},
+		{name: "a gang beyond its min preempts nothing", lenderMin: "1", borrowerMin: "0",
+			expected: []string{"new-0", "new-1", "old-0", "old-1"}},
+		{name: "gangs on nodes the lender can not use are not preempted", lenderMin: "4", borrowerMin: "0",
+			rejected: []string{"node2"}, expected: []string{"new-0", "new-1"}, nominated: "node1"},
+		{name: "nothing is preempted when the lender can use no node", lenderMin: "4", borrowerMin: "0",
+			rejected: []string{"node1", "node2"}, expected: []string{"new-0", "new-1", "old-0", "old-1"}},
+		{name: "gangs outside the zone chosen for the lender are not preempted", lenderMin: "4", borrowerMin: "0",
+			zone: true, expected: []string{"new-0", "new-1"}, nominated: "node1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			running := append([]*corev1.Pod{}, running...)
+			for i, p := range running {
+				for _, group := range tt.marked {
+					if p.Labels[PodGroupName] == group {
+						running[i] = p.DeepCopy()
+						running[i].Annotations = map[string]string{v1alpha1.BorrowedAnnotation: string(gpu)}
+					}
+				}
+			}
+			var objs []runtime.Object
+			nominator := newFakePodNominator()
+			for _, p := range running {
+				objs = append(objs, p.DeepCopy())
+			}
+			for _, p := range nominated {
+				objs = append(objs, p.DeepCopy())
+				nominator.AddNominatedPod(framework.NewPodInfo(p), p.Status.NominatedNodeName)
+			}
+			cs := clientsetfake.NewSimpleClientset(objs...)
+			nodeInfos := []*framework.NodeInfo{gpuNode("node1", "2", running[:2]...), gpuNode("node2", "2", running[2:]...)}
+			nodeInfos[0].Node().Labels = map[string]string{corev1.LabelTopologyZone: "a"}
+			nodeInfos[1].Node().Labels = map[string]string{corev1.LabelTopologyZone: "b"}
+			lender := group("lender", "pg", 0)
+			if tt.zone {
+				lender.Data[requiredTopologyKeys] = corev1.LabelTopologyZone
+			}
+
+			var s *Sample
+			recorder := events.NewFakeRecorder(100)
+			registry := framework_rt.Registry{}
+			if err := registry.Register(Name, func(_ runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+				s = newFakeSample(handle)
+				s.events = newEventRecorder(recorder)
+				s.cmLister = clientv1.NewConfigMapLister(newIndexer(
+					group("borrower", "old", 2*time.Minute), group("borrower", "new", time.Minute), lender))
+				s.queueLister = pglisters.NewQueueLister(newIndexer(queue("borrower", tt.borrowerMin), queue("lender", tt.lenderMin)))
+				addPods(s, append(append([]*corev1.Pod{}, running...), pending...)...)
+				for _, p := range running {
+					s.queues.setPod(s.queueName(p), p)
+				}
+				return s, nil
+			}); err != nil {
+				t.Fatalf("fail to register postfilter plugin (%s)", Name)
+			}
+			registry[nodeFilterPlugin] = newNodeFilterPlugin(tt.rejected...)
+			cfgPls := &config.Plugins{
+				Filter:     config.PluginSet{Enabled: []config.Plugin{{Name: Name}, {Name: nodeFilterPlugin}}},
+				PostFilter: config.PluginSet{Enabled: []config.Plugin{{Name: Name}}},
+			}
+			f, err := newFrameworkWithQueueSortAndBind(registry, cfgPls, emptyArgs,
+				framework_rt.WithClientSet(cs),
+				framework_rt.WithInformerFactory(informers.NewSharedInformerFactory(cs, 0)),
+				framework_rt.WithSnapshotSharedLister(&fakeSharedLister{nodeInfos: nodeInfos}),
+				framework_rt.WithPodNominator(nominator))
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+
+			// The lender does not fit in the cluster in PreFilter.
+			state := framework.NewCycleState()
+			state.Write(preFilterStateKey, &preFilterState{outOfCapacity: true})
+			result, status := f.RunPostFilterPlugins(context.TODO(), state, pending[0], nil)
+			if tt.nominated == "" {
+				if status.Code() != framework.Unschedulable || result != nil {
+					t.Errorf("expected %v and no nomination, got %v and %v", framework.Unschedulable, status.Code(), result)
+				}
+			} else if !status.IsSuccess() || result == nil || result.NominatedNodeName != tt.nominated {
+				t.Errorf("expected to be nominated to %v, got %v and %v", tt.nominated, status, result)
+			}
+			pods, err := cs.CoreV1().Pods("borrower").List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var left []string
+			for _, p := range pods.Items {
+				left = append(left, p.Name)
+			}
+			sort.Strings(left)
+			if diff := cmp.Diff(tt.expected, left); diff != "" {
+				t.Errorf("unexpected pods left (-want,+got): %s", diff)
+			}
+			// The victims are told why with an event, their status is left
+			// alone.
+			reclaimed := 0
+			for _, e := range recordedEvents(recorder) {
+				if strings.HasPrefix(e, corev1.EventTypeWarning+" "+reasonReclaimed+" ") {
+					reclaimed++
+				}
+			}
+			if preempted := len(running) - len(tt.expected); reclaimed != preempted+preempted/2 {
+				t.Errorf("expected %v events on %d victims and their group, got %d", reasonReclaimed, preempted, reclaimed)
+			}
+			for _, action := range cs.Actions() {
+				if action.GetNamespace() == "borrower" && action.GetSubresource() == "status" {
+					t.Errorf("unexpected update of the status of a victim: %v", action)
+				}
+			}
+			// Only the pod of lower priority nominated to the same node loses
+			// its nomination.
+			for _, p := range nominated {
+				got, err := cs.CoreV1().Pods(p.Namespace).Get(context.TODO(), p.Name, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if cleared := got.Status.NominatedNodeName == ""; cleared != (p.Status.NominatedNodeName == tt.nominated) {
+					t.Errorf("expected the nomination of %v cleared %v, got %q", p.Name, !cleared, got.Status.NominatedNodeName)
+				}
+			}
+
+			// While the lender reclaims its min, the borrower can not borrow
+			// it again.
+			again := member("borrower", "new", "new-2", "")
+			addPods(s, again)
+			quota, err := s.checkQuota(again, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if reclaiming := len(tt.expected) < len(running); reclaiming != (quota.msg != "") {
+				t.Errorf("expected the borrower to be refused %v, got %q", reclaiming, quota.msg)
+			}
+		})
+	}
+}
+
+func TestMarkBorrowed(t *testing.T) {
+	oneGPU := corev1.ResourceList{gpu: resource.MustParse("1")}
+	alone := gangMember("alone", oneGPU)
+	delete(alone.Labels, PodGroupName)
+	members := []*corev1.Pod{gangMember("member-0", oneGPU), gangMember("member-1", oneGPU)}
+	within := gangMember("within", oneGPU)
+	within.Labels = map[string]string{defaultArgs().QueueLabelKey: "team"}
+	pods := append([]*corev1.Pod{alone, within}, members...)
+	var objs []runtime.Object
+	for _, p := range pods {
+		objs = append(objs, p.DeepCopy())
+	}
+	cs := clientsetfake.NewSimpleClientset(objs...)
+
+	var s *Sample
+	registry := framework_rt.Registry{}
+	if err := registry.Register(Name, func(_ runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+		s = newFakeSample(handle)
+		s.cmLister = clientv1.NewConfigMapLister(newIndexer(
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "ns"}, Data: map[string]string{minAvailable: "2"}}))
+		// ns guarantees nothing, team 1 gpu.
+		s.queueLister = pglisters.NewQueueLister(newIndexer(
+			&v1alpha1.Queue{ObjectMeta: metav1.ObjectMeta{Name: "ns"}, Spec: v1alpha1.QueueSpec{
+				Max: corev1.ResourceList{gpu: resource.MustParse("8")}}},
+			&v1alpha1.Queue{ObjectMeta: metav1.ObjectMeta{Name: "team"}, Spec: v1alpha1.QueueSpec{
+				Min: corev1.ResourceList{gpu: resource.MustParse("1")}, Max: corev1.ResourceList{gpu: resource.MustParse("8")}}}))
+		addPods(s, pods...)
+		return s, nil
+	}); err != nil {
+		t.Fatalf("fail to register plugin (%s)", Name)
+	}
+	cfgPls := &config.Plugins{PreFilter: config.PluginSet{Enabled: []config.Plugin{{Name: Name}}}}
+	if _, err := newFrameworkWithQueueSortAndBind(registry, cfgPls, emptyArgs,
+		framework_rt.WithClientSet(cs),
+		framework_rt.WithInformerFactory(informers.NewSharedInformerFactory(cs, 0)),
+		framework_rt.WithSnapshotSharedLister(&fakeSharedLister{nodeInfos: []*framework.NodeInfo{gpuNode("node1", "8")}})); err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+
+	// The members of a gang admitted beyond its min are all marked, the
+	// second one although it is only counted with the first one.
+	for _, tt := range []struct {
+		pod      *corev1.Pod
+		expected string
+	}{
+		{pod: alone, expected: string(gpu)},
+		{pod: members[0], expected: string(gpu)},
+		{pod: members[1], expected: string(gpu)},
+		{pod: within},
+	} {
+		state := framework.NewCycleState()
+		if status := s.PreFilter(context.TODO(), state, tt.pod); !status.IsSuccess() {
+			t.Fatalf("expected %v to pass PreFilter, got %v", tt.pod.Name, status)
+		}
+		s.PostBind(context.TODO(), state, tt.pod, "node1")
+		got, err := cs.CoreV1().Pods(tt.pod.Namespace).Get(context.TODO(), tt.pod.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if marked := got.Annotations[v1alpha1.BorrowedAnnotation]; marked != tt.expected {
+			t.Errorf("expected %v marked as borrowing %q, got %q", tt.pod.Name, tt.expected, marked)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package sample
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// role returns the role of pod, empty if it has none.
+func (s *Sample) role(pod *v1.Pod) string {
+	return pod.Labels[s.args.RoleLabelKey]
+}
+
+// validateMinRoles returns why the minRoles of pg are invalid, empty if they
+// are valid. Every role needs at least one member, and together they must
+// not need more members than minAvailable.
+func validateMinRoles(pg *podGroup) string {
+	sum := 0
+	for _, role := range sortedRoles(pg) {
+		if role == "" {
+			return fmt.Sprintf("minRoles of podGroup %v/%v has an empty role", pg.namespace, pg.name)
+		}
+		if pg.minRoles[role] < 1 {
+			return fmt.Sprintf("minRoles of podGroup %v/%v must be at least 1, got %d for role %v",
+				pg.namespace, pg.name, pg.minRoles[role], role)
+		}
+		sum += pg.minRoles[role]
+	}
+	if sum > pg.minAvailable {
+		return fmt.Sprintf("minRoles of podGroup %v/%v must not add up to more than minAvailable(%d), got %d",
+			pg.namespace, pg.name, pg.minAvailable, sum)
+	}
+	return ""
+}
+
+// sortedRoles returns the roles pg sets a minimum for, sorted.
+func sortedRoles(pg *podGroup) []string {
+	roles := make([]string, 0, len(pg.minRoles))
+	for role := range pg.minRoles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// unmetRoles returns the roles of pg, sorted, whose members counted by count
+// are fewer than their minimum.
+func unmetRoles(pg *podGroup, roles map[string]roleCounts, count func(role string, c roleCounts) int) []string {
+	var unmet []string
+	for _, role := range sortedRoles(pg) {
+		if count(role, roles[role]) < pg.minRoles[role] {
+			unmet = append(unmet, role)
+		}
+	}
+	return unmet
+}
+
+// formatRoles formats the members of each role of pg counted by count
+// against their minimum, e.g. "ps(0/1) worker(5/4)".
+func formatRoles(pg *podGroup, roles map[string]roleCounts, count func(role string, c roleCounts) int) string {
+	parts := make([]string, 0, len(pg.minRoles))
+	for _, role := range sortedRoles(pg) {
+		parts = append(parts, fmt.Sprintf("%v(%d/%d)", role, count(role, roles[role]), pg.minRoles[role]))
+	}
+	return strings.Join(parts, " ")
+}
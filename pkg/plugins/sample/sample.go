@@ -3,60 +3,287 @@ package sample
 import (
 	"context"
 	"fmt"
-	"k8s.io/apimachinery/pkg/labels"
-	"strconv"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/dynamic"
 	clientv1 "k8s.io/client-go/listers/core/v1"
+	schedulingv1 "k8s.io/client-go/listers/scheduling/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config"
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config/v1beta1"
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config/validation"
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+	pginformers "github.com/FFFFFaraway/gang-scheduler/pkg/generated/informers/externalversions"
+	pglisters "github.com/FFFFFaraway/gang-scheduler/pkg/generated/listers/scheduling/v1alpha1"
 )
 
 const (
 	// Name is plugin name
-	Name                   = "sample"
-	PodGroupName           = "pod-group.scheduling.bdap.com/podgroup-configmap"
-	minAvailable           = "minAvailable"
-	scheduleTimeoutSeconds = "scheduleTimeoutSeconds"
+	Name = "sample"
+	// PodGroupName is the default label naming the group of a pod, see
+	// SampleArgs.GroupLabelKey.
+	PodGroupName = v1beta1.DefaultGroupLabelKey
+	// PodGroupRole is the default label naming the role of a member, see
+	// SampleArgs.RoleLabelKey.
+	PodGroupRole           = v1beta1.DefaultRoleLabelKey
+	minAvailable           = v1alpha1.MinAvailableKey
+	maxMember              = v1alpha1.MaxMemberKey
+	minRoles               = v1alpha1.MinRolesKey
+	placement              = v1alpha1.PlacementKey
+	requiredTopologyKeys   = v1alpha1.RequiredTopologyKeysKey
+	scheduleTimeoutSeconds = v1alpha1.ScheduleTimeoutSecondsKey
+	priorityClassName      = v1alpha1.PriorityClassNameKey
 )
 
 var _ framework.QueueSortPlugin = &Sample{}
+var _ framework.PreFilterPlugin = &Sample{}
+var _ framework.FilterPlugin = &Sample{}
+var _ framework.PostFilterPlugin = &Sample{}
+var _ framework.PreScorePlugin = &Sample{}
+var _ framework.ScorePlugin = &Sample{}
+var _ framework.ReservePlugin = &Sample{}
 var _ framework.PermitPlugin = &Sample{}
+var _ framework.PostBindPlugin = &Sample{}
 
 type Sample struct {
-	handle    framework.Handle
-	cmLister  clientv1.ConfigMapLister
-	podLister clientv1.PodLister
+	handle   framework.Handle
+	args     *config.SampleArgs
+	cmLister clientv1.ConfigMapLister
+	pcLister schedulingv1.PriorityClassLister
+	// pgLister is nil when the PodGroup CRD is not installed.
+	pgLister pglisters.PodGroupLister
+	// queueLister is nil when the Queue CRD is not installed, pods are not
+	// limited then.
+	queueLister pglisters.QueueLister
+	gangs       *gangManager
+	queues      *queueManager
+	// foreignSources read the PodGroups of other schedulers.
+	foreignSources []groupSource
+	status         *statusUpdater
+	// capacityCache shares the capacity simulation of a group between siblings.
+	capacityCache *utilcache.LRUExpireCache
+	// denials are the groups rejected as a unit, see denialTTL.
+	denials *utilcache.LRUExpireCache
+	backoff *groupBackoff
+	events  *eventRecorder
+	// reclaims are the mins of queues being reclaimed, see reclaim.
+	reclaims *utilcache.LRUExpireCache
 }
 
-func New(_ runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+// NewFactory returns the factory of the plugin. The clients it makes besides
+// the ones of the handle use the config kubeConfig returns, the one of the
+// scheduler, and the informers they feed run until stopCh is closed. The
+// plugins of every profile are served by debug, which may be nil.
+func NewFactory(kubeConfig func() (*rest.Config, error), debug *DebugServer, stopCh <-chan struct{}) frameworkruntime.PluginFactory {
+	return func(obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+		return New(obj, handle, kubeConfig, debug, stopCh)
+	}
+}
+
+// New returns the plugin, see NewFactory.
+func New(obj runtime.Object, handle framework.Handle, kubeConfig func() (*rest.Config, error), debug *DebugServer, stopCh <-chan struct{}) (framework.Plugin, error) {
+	args, err := getArgs(obj)
+	if err != nil {
+		return nil, err
+	}
+	registerMetrics()
 	cmLister := handle.SharedInformerFactory().Core().V1().ConfigMaps().Lister()
-	podLister := handle.SharedInformerFactory().Core().V1().Pods().Lister()
-	return &Sample{
-		handle:    handle,
-		cmLister:  cmLister,
-		podLister: podLister,
-	}, nil
+	pcLister := handle.SharedInformerFactory().Scheduling().V1().PriorityClasses().Lister()
+	restConfig, err := kubeConfig()
+	if err != nil {
+		return nil, err
+	}
+	pgClient, err := newPodGroupClient(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	s := &Sample{
+		handle:   handle,
+		args:     args,
+		cmLister: cmLister,
+		pcLister: pcLister,
+		gangs:    newGangManager(),
+		queues:   newQueueManager(),
+		status:   newStatusUpdater(pgClient),
+
+		capacityCache: newCapacityCache(),
+		denials:       newDenialCache(),
+		reclaims:      newReclaimCache(),
+		backoff: newGroupBackoff(time.Duration(args.BackoffInitialSeconds)*time.Second,
+			time.Duration(args.BackoffMaxSeconds)*time.Second),
+		events: newEventRecorder(handle.EventRecorder()),
+	}
+
+	var pgInformer cache.SharedIndexInformer
+	if pgClient != nil {
+		pgInformerFactory := pginformers.NewSharedInformerFactory(pgClient, 0)
+		pgInformer = pgInformerFactory.Scheduling().V1alpha1().PodGroups().Informer()
+		s.pgLister = pgInformerFactory.Scheduling().V1alpha1().PodGroups().Lister()
+		pgInformer.AddEventHandler(s.groupObjectEventHandler(specChanged))
+		queuesServed, err := resourceServed(pgClient.Discovery(), v1alpha1.SchemeGroupVersion.WithResource("queues"))
+		if err != nil {
+			return nil, err
+		}
+		if queuesServed {
+			s.queueLister = pgInformerFactory.Scheduling().V1alpha1().Queues().Lister()
+		} else {
+			klog.Warningf("queues of %v are not served by the apiserver, pods are not limited by queues", v1alpha1.SchemeGroupVersion)
+		}
+		pgInformerFactory.Start(stopCh)
+		for informerType, synced := range pgInformerFactory.WaitForCacheSync(syncStop(stopCh)) {
+			if !synced {
+				return nil, fmt.Errorf("timed out waiting for the cache of %v to sync", informerType)
+			}
+		}
+	}
+	if s.foreignSources, err = newForeignSources(args.ForeignPodGroups, dynamicClient, handle.ClientSet().Discovery(),
+		s.groupObjectEventHandler(specChanged), stopCh); err != nil {
+		return nil, err
+	}
+	informerFactory := handle.SharedInformerFactory()
+	informerFactory.Core().V1().Pods().Informer().AddEventHandler(s.gangPodEventHandler())
+	informerFactory.Core().V1().Pods().Informer().AddEventHandler(s.queuePodEventHandler())
+	informerFactory.Core().V1().ConfigMaps().Informer().AddEventHandler(s.configMapEventHandler())
+	informerFactory.Scheduling().V1().PriorityClasses().Informer().AddEventHandler(s.priorityClassEventHandler())
+	s.addStatusEventHandlers(pgInformer)
+	s.runStatusUpdater(stopCh)
+	if debug != nil {
+		if err := debug.register(s, stopCh); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// getArgs returns the validated args of the plugin. The scheduler passes none
+// when the args are not registered in its scheme, the defaults are used then.
+func getArgs(obj runtime.Object) (*config.SampleArgs, error) {
+	if obj == nil {
+		return defaultArgs(), nil
+	}
+	args, ok := obj.(*config.SampleArgs)
+	if !ok {
+		return nil, fmt.Errorf("want args to be of type SampleArgs, got %T", obj)
+	}
+	if err := validation.ValidateSampleArgs(args); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+func defaultArgs() *config.SampleArgs {
+	var v1beta1Args v1beta1.SampleArgs
+	v1beta1.SetDefaults_SampleArgs(&v1beta1Args)
+	var args config.SampleArgs
+	// The conversion of these types never fails.
+	_ = v1beta1.Convert_v1beta1_SampleArgs_To_config_SampleArgs(&v1beta1Args, &args, nil)
+	return &args
 }
 
 func (s *Sample) Name() string {
 	return Name
 }
 
-func (s *Sample) pgTime(p *v1.Pod) (time.Time, bool) {
-	pg, exist := p.Labels[PodGroupName]
-	if !exist || pg == "" {
-		return time.Time{}, false
+// groupName returns the name of the group of pod, empty if it is in none. The
+// keys are looked at in order: GroupLabelKey, GroupLabelKeys, then
+// GroupAnnotationKeys, so that pods made for coscheduling or Volcano are
+// understood too.
+func (s *Sample) groupName(pod *v1.Pod) string {
+	if name := pod.Labels[s.args.GroupLabelKey]; name != "" {
+		return name
+	}
+	for _, key := range s.args.GroupLabelKeys {
+		if name := pod.Labels[key]; name != "" {
+			return name
+		}
 	}
-	cm, err := s.cmLister.ConfigMaps(p.Namespace).Get(pg)
+	for _, key := range s.args.GroupAnnotationKeys {
+		if name := pod.Annotations[key]; name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// groupMembers returns the pods of the group namespace/name.
+func (s *Sample) groupMembers(namespace, name string) []*v1.Pod {
+	return s.gangs.members(namespace + "/" + name)
+}
+
+// holding returns the number of members of the group key holding a node:
+// bound, assumed or waiting in Permit.
+func (s *Sample) holding(key string) int {
+	return s.gangs.counts(key).bound + s.gangs.waiting(key)
+}
+
+// formed returns whether the gang of the group key is formed: minAvailable
+// members, and the minimum of every role, got their node.
+func (s *Sample) formed(key string, pg *podGroup) bool {
+	countSucceeded := s.countSucceeded()
+	if s.gangs.counts(key).scheduled(countSucceeded) < pg.minAvailable {
+		return false
+	}
+	if len(pg.minRoles) == 0 {
+		return true
+	}
+	roles := s.gangs.roleCounts(key, s.args.RoleLabelKey)
+	return len(unmetRoles(pg, roles, func(_ string, c roleCounts) int { return c.scheduled(countSucceeded) })) == 0
+}
+
+// countSucceeded returns whether the succeeded members of a group count
+// towards its minAvailable.
+func (s *Sample) countSucceeded() bool {
+	return s.args.SucceededMembers != config.SucceededMembersIgnore
+}
+
+// queueSortKey is what Less orders pods by before regPodLess. All the members
+// of a group share the same key.
+type queueSortKey struct {
+	priority int32
+	inGroup  bool
+	created  time.Time
+	group    string
+}
+
+// queueSortKey returns the key of p: a pod in a group takes the priority,
+// creation time and namespace/name of its group, other pods their own
+// priority.
+func (s *Sample) queueSortKey(p *v1.Pod) queueSortKey {
+	pgName := s.groupName(p)
+	if pgName == "" {
+		return queueSortKey{priority: corev1helpers.PodPriority(p)}
+	}
+	key := p.Namespace + "/" + pgName
+	cached, token := s.gangs.cachedSortKey(key)
+	if cached != nil {
+		return *cached
+	}
+	pg, err := s.getPodGroup(p.Namespace, pgName)
 	if err != nil {
-		return time.Time{}, false
+		return queueSortKey{priority: corev1helpers.PodPriority(p)}
 	}
-	return cm.CreationTimestamp.Time, true
+	sortKey := queueSortKey{
+		priority: s.priority(pg),
+		inGroup:  true,
+		created:  pg.creationTime,
+		group:    key,
+	}
+	s.gangs.cacheSortKey(key, token, sortKey)
+	return sortKey
 }
 
 func regPodLess(p1 *framework.QueuedPodInfo, p2 *framework.QueuedPodInfo) bool {
@@ -68,94 +295,434 @@ func regPodLess(p1 *framework.QueuedPodInfo, p2 *framework.QueuedPodInfo) bool {
 	return p1.InitialAttemptTimestamp.Before(p2.InitialAttemptTimestamp)
 }
 
+// Less orders pods by the priority of their group, or their own priority when
+// they are not in a group. Then pods that are not in a group go first, then
+// groups by creation time and by key, then the members of a group by
+// regPodLess. Each level is a strict weak ordering and every member of a group
+// maps to the same queueSortKey, so the whole is a strict weak ordering too,
+// and the members of a group are always next to each other in the queue.
 func (s *Sample) Less(p1 *framework.QueuedPodInfo, p2 *framework.QueuedPodInfo) bool {
-	pgt1, exist1 := s.pgTime(p1.Pod)
-	pgt2, exist2 := s.pgTime(p1.Pod)
+	k1 := s.queueSortKey(p1.Pod)
+	k2 := s.queueSortKey(p2.Pod)
+	if k1.priority != k2.priority {
+		return k1.priority > k2.priority
+	}
 	// One is in pg while the other is not.
 	// Then p1 first if p1 is not in a pod group
-	if exist1 != exist2 {
-		return !exist1
+	if k1.inGroup != k2.inGroup {
+		return !k1.inGroup
 	}
 	// Neither in pod group
-	if !exist1 {
+	if !k1.inGroup {
 		return regPodLess(p1, p2)
 	}
-	if !pgt1.Equal(pgt2) {
-		return pgt1.Before(pgt2)
+	if !k1.created.Equal(k2.created) {
+		return k1.created.Before(k2.created)
+	}
+	// Groups created in the same second must not be interleaved.
+	if k1.group != k2.group {
+		return k1.group < k2.group
 	}
 	return regPodLess(p1, p2)
 }
 
-func (s *Sample) Permit(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (*framework.Status, time.Duration) {
-	podGroupName, exist := pod.Labels[PodGroupName]
-	if !exist || podGroupName == "" {
-		return framework.NewStatus(framework.Success, ""), 0
+// preFilterStateKey is the key in the cycle state of how the pod went
+// through PreFilter.
+const preFilterStateKey framework.StateKey = Name + "/prefilter"
+
+// preFilterState tells PostFilter why the pod is unschedulable. There is none
+// when PreFilter denied the pod for a reason no preemption helps with.
+type preFilterState struct {
+	// passed is set when the pod passed PreFilter, and so failed Filter.
+	passed bool
+	// outOfCapacity is set when the gang of the pod does not fit in the
+	// cluster, preempting may make room for it.
+	outOfCapacity bool
+}
+
+func (p *preFilterState) Clone() framework.StateData {
+	return p
+}
+
+// PreFilter rejects the members of a group right away while fewer than
+// minAvailable of them, or than the minimum of one of its roles, have been
+// created, or while the members that still
+// need a node can not all fit in the cluster, so that they do not hold nodes
+// in Permit for a gang that can not be complete. A gang, or a pod not in a
+// group, taking its queue beyond its max is rejected too. The denials that
+// preempting pods can not help with are UnschedulableAndUnresolvable.
+func (s *Sample) PreFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod) *framework.Status {
+	podGroupName := s.groupName(pod)
+	if podGroupName == "" {
+		if s.args.RequireGroup {
+			return framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf("pod is not in a group, please set the %v label", s.args.GroupLabelKey))
+		}
+		quota, status := s.quotaStatus(pod, nil)
+		if status.IsSuccess() {
+			state.Write(borrowedStateKey, &borrowedState{resources: quota.borrowed})
+			state.Write(preFilterStateKey, &preFilterState{passed: true})
+		}
+		return status
 	}
-	cm, err := s.cmLister.ConfigMaps(pod.Namespace).Get(podGroupName)
+	if d := s.backoff.remaining(pod.Namespace + "/" + podGroupName); d > 0 {
+		msg := fmt.Sprintf("podGroup %v/%v is backing off for %v after it failed to assemble", pod.Namespace, podGroupName, d.Round(time.Second))
+		klog.V(3).Info(msg)
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, msg)
+	}
+	if denied, ok := s.denials.Get(pod.Namespace + "/" + podGroupName); ok {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, denied.(string))
+	}
+	pg, err := s.getPodGroup(pod.Namespace, podGroupName)
 	if apierrors.IsNotFound(err) {
-		klog.Errorf("podgroup %v configmap not found in %v", podGroupName, pod.Namespace)
-		return framework.NewStatus(framework.Error, "podgroup configmap not found, please create configmap first"), 0
+		msg := fmt.Sprintf("podGroup %v/%v not found, please create podgroup or configmap first", pod.Namespace, podGroupName)
+		s.events.podEvent(pod, v1.EventTypeWarning, reasonNotFound, msg)
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, msg)
+	}
+	if invalid, ok := asInvalidGroup(err); ok {
+		s.events.podEvent(pod, v1.EventTypeWarning, reasonInvalid, invalid.msg)
+		s.events.refEvent(invalid.ref, v1.EventTypeWarning, reasonInvalid, invalid.msg)
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, invalid.msg)
 	}
 	if err != nil {
-		return framework.NewStatus(framework.Error, err.Error()), 0
+		return framework.NewStatus(framework.Error, err.Error())
+	}
+	key := pod.Namespace + "/" + podGroupName
+	if pg.maxMember > 0 {
+		// Held until a member leaves, the gang does not need it to form.
+		if holding := s.holding(key); holding >= pg.maxMember {
+			msg := fmt.Sprintf("podGroup %v/%v reached maxMember(%d) in PreFilter: holding(%d)",
+				pod.Namespace, podGroupName, pg.maxMember, holding)
+			klog.V(3).Info(msg)
+			return framework.NewStatus(framework.UnschedulableAndUnresolvable, msg)
+		}
+	}
+	quota, status := s.quotaStatus(pod, pg)
+	if !status.IsSuccess() {
+		return status
+	}
+	if pg.minAvailable <= 1 && len(pg.minRoles) == 0 && len(pg.requiredTopologyKeys) == 0 {
+		s.admitQuota(state, pod, pg, quota)
+		state.Write(preFilterStateKey, &preFilterState{passed: true})
+		return framework.NewStatus(framework.Success, "")
 	}
 
-	maStr, exist := cm.Data[minAvailable]
-	if !exist {
-		return framework.NewStatus(framework.Error, "minAvailable field not found in podgroup configmap"), 0
+	countSucceeded := s.countSucceeded()
+	available := s.gangs.counts(key).available(countSucceeded)
+	if available < pg.minAvailable {
+		msg := fmt.Sprintf("The count of podGroup %v/%v/%v is not up to minAvailable(%d) in PreFilter: available(%d)",
+			pod.Namespace, podGroupName, pod.Name, pg.minAvailable, available)
+		klog.V(3).Info(msg)
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, msg)
+	}
+	if len(pg.minRoles) > 0 {
+		roles := s.gangs.roleCounts(key, s.args.RoleLabelKey)
+		availableOf := func(_ string, c roleCounts) int { return c.available(countSucceeded) }
+		if unmet := unmetRoles(pg, roles, availableOf); len(unmet) > 0 {
+			msg := fmt.Sprintf("The roles %v of podGroup %v/%v/%v are not up to their minimums in PreFilter: available %v",
+				unmet, pod.Namespace, podGroupName, pod.Name, formatRoles(pg, roles, availableOf))
+			klog.V(3).Info(msg)
+			return framework.NewStatus(framework.UnschedulableAndUnresolvable, msg)
+		}
 	}
-	ma, err := strconv.Atoi(maStr)
+
+	capacity, err := s.checkCapacity(pod, pg)
 	if err != nil {
-		return framework.NewStatus(framework.Error, err.Error()), 0
+		return framework.NewStatus(framework.Error, err.Error())
 	}
-	if ma <= 1 {
-		return framework.NewStatus(framework.Success, ""), 0
+	if !capacity.fits {
+		klog.V(3).Info(capacity.msg)
+		state.Write(preFilterStateKey, &preFilterState{outOfCapacity: true})
+		return framework.NewStatus(framework.Unschedulable, capacity.msg)
 	}
+	s.admitQuota(state, pod, pg, quota)
+	state.Write(preFilterStateKey, &preFilterState{passed: true})
+	return framework.NewStatus(framework.Success, "")
+}
 
-	sts := 10
-	stsStr, exist := cm.Data[scheduleTimeoutSeconds]
-	if exist {
-		sts, err = strconv.Atoi(stsStr)
-		if err != nil {
-			return framework.NewStatus(framework.Error, err.Error()), 0
+// quotaStatus rejects pod when it, or the gang of pg, would take its queue
+// beyond its max, see checkQuota. A gang borrowing beyond the min of its queue
+// is told it may be preempted. The result is returned to admit the gang with.
+func (s *Sample) quotaStatus(pod *v1.Pod, pg *podGroup) (*quotaResult, *framework.Status) {
+	result, err := s.checkQuota(pod, pg)
+	if err != nil {
+		return nil, framework.NewStatus(framework.Error, err.Error())
+	}
+	if result.msg != "" {
+		klog.V(3).Info(result.msg)
+		return nil, framework.NewStatus(framework.UnschedulableAndUnresolvable, result.msg)
+	}
+	if len(result.borrowed) > 0 {
+		what := fmt.Sprintf("pod %v/%v", pod.Namespace, pod.Name)
+		if pg != nil {
+			what = fmt.Sprintf("podGroup %v/%v", pg.namespace, pg.name)
 		}
+		msg := fmt.Sprintf("%v borrows %v beyond the min of queue %v, it may be preempted to give them back",
+			what, result.borrowed, s.queueName(pod))
+		s.events.podEvent(pod, v1.EventTypeNormal, reasonBorrowing, msg)
+		s.events.groupEvent(pg, v1.EventTypeNormal, reasonBorrowing, msg)
 	}
+	return result, framework.NewStatus(framework.Success, "")
+}
 
-	namespace := pod.Namespace
+// admitQuota admits the gang of pod against the quota of its queue once pod
+// passed PreFilter, until the gang reaches its quorum or is rolled back, or
+// for the scheduleTimeout of pg if it never gets that far. What the gang
+// borrows is written to the cycle state, for PostBind to mark pod with.
+func (s *Sample) admitQuota(state *framework.CycleState, pod *v1.Pod, pg *podGroup, quota *quotaResult) {
+	key := pg.namespace + "/" + pg.name
+	s.queues.admit(key, s.queueName(pod), quota.members, quota.borrowed, pg.scheduleTimeout)
+	state.Write(borrowedStateKey, &borrowedState{resources: unionResources(quota.borrowed, s.queues.borrowed(key))})
+}
+
+func (s *Sample) PreFilterExtensions() framework.PreFilterExtensions {
+	return nil
+}
+
+// PostFilter rejects the waiting siblings of a member that failed Filter, so
+// that the gang gives its nodes back at once and is retried as a unit. A gang
+// that fits in the min of its queue preempts the gangs borrowing it first, see
+// reclaim, and pod is nominated to a node they free, when the member failed
+// Filter or its gang does not fit in the cluster. Nothing is done for a
+// member PreFilter denied for another reason, e.g. while its gang gathers its
+// quorum.
+func (s *Sample) PostFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, _ framework.NodeToStatusMap) (*framework.PostFilterResult, *framework.Status) {
+	podGroupName := s.groupName(pod)
+	if podGroupName == "" {
+		return nil, framework.NewStatus(framework.Unschedulable, "")
+	}
+	data, err := state.Read(preFilterStateKey)
+	if err != nil {
+		return nil, framework.NewStatus(framework.Unschedulable, "")
+	}
+	filterFailed := data.(*preFilterState).passed
+	pg, err := s.getPodGroup(pod.Namespace, podGroupName)
+	if err != nil {
+		return nil, framework.NewStatus(framework.Unschedulable, "")
+	}
 
-	running := 0
-	selector := labels.Set{PodGroupName: podGroupName}.AsSelector()
-	pods, err := s.podLister.Pods(namespace).List(selector)
-	for _, p := range pods {
-		if p.Status.Phase == v1.PodRunning {
-			running++
+	// The gang is already complete, this member is only one of the extras.
+	key := pod.Namespace + "/" + podGroupName
+	if s.formed(key, pg) || pg.maxMember > 0 && s.holding(key) >= pg.maxMember {
+		return nil, framework.NewStatus(framework.Unschedulable, "")
+	}
+
+	reclaimed, nominated := s.reclaim(ctx, state, pod, pg)
+	msg := reclaimed
+	if filterFailed {
+		msg = fmt.Sprintf("podGroup %v/%v is rejected because member %v is unschedulable", pod.Namespace, podGroupName, pod.Name)
+		if reclaimed != "" {
+			msg += ", " + reclaimed
 		}
+		s.rejectWaitingPods(pod.Namespace, podGroupName, msg)
+		// Deny the other members in PreFilter for a while, so they do not grab
+		// nodes one by one before the gang is retried.
+		s.denials.Add(pod.Namespace+"/"+podGroupName, msg, denialTTL)
+	}
+	if nominated != "" {
+		return &framework.PostFilterResult{NominatedNodeName: nominated}, framework.NewStatus(framework.Success, msg)
 	}
+	return nil, framework.NewStatus(framework.Unschedulable, msg)
+}
 
-	waiting := 0
-	s.handle.IterateOverWaitingPods(func(wp framework.WaitingPod) {
-		if wp.GetPod().Labels[PodGroupName] == podGroupName && wp.GetPod().Namespace == namespace {
-			waiting++
+// rejectWaitingPods rolls back the current attempt of the group: it rejects
+// every member held in Permit. The members are marked as rejected before, so
+// that their Unreserve, which runs asynchronously, does not roll the attempt
+// back again. It returns false if the attempt is already rolled back.
+func (s *Sample) rejectWaitingPods(namespace, podGroupName, msg string) bool {
+	names, started := s.gangs.rollBack(namespace + "/" + podGroupName)
+	if !started {
+		return false
+	}
+	s.handle.IterateOverWaitingPods(func(waitingPod framework.WaitingPod) {
+		pod := waitingPod.GetPod()
+		if pod.Namespace == namespace && names.Has(pod.Name) && s.groupName(pod) == podGroupName {
+			klog.V(3).Infof("Reject the waiting pod %v/%v: %v", namespace, pod.Name, msg)
+			waitingPod.Reject(s.Name(), msg)
 		}
 	})
+	if names.Len() > 0 {
+		s.status.enqueue(namespace, podGroupName)
+	}
+	return true
+}
 
-	current := running + waiting + 1
+// Reserve counts the resources of pod against its queue right away, so that
+// the pods scheduled before its binding is seen do not take its quota. A pod
+// whose queue does not exist has no quota to take.
+func (s *Sample) Reserve(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) *framework.Status {
+	queue := s.queueName(pod)
+	if q, err := s.getQueue(queue); err == nil && q == nil {
+		return framework.NewStatus(framework.Success, "")
+	}
+	s.queues.reserve(queue, pod)
+	return framework.NewStatus(framework.Success, "")
+}
 
-	if current < ma {
-		msg := fmt.Sprintf("The count of podGroup %v/%v/%v is not up to minAvailable(%d) in Permit: running(%d), waiting(%d)",
-			pod.Namespace, podGroupName, pod.Name, ma, running, waiting)
+// Unreserve rolls the whole gang back when one of its members is given up,
+// e.g. because its wait in Permit timed out, so that a gang never keeps only
+// part of its nodes.
+func (s *Sample) Unreserve(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) {
+	s.queues.unreserve(pod)
+	podGroupName := s.groupName(pod)
+	if podGroupName == "" {
+		return
+	}
+	key := pod.Namespace + "/" + podGroupName
+	pg, err := s.getPodGroup(pod.Namespace, podGroupName)
+	held, rejected := s.gangs.unreserve(key, pod.Name)
+	if rejected {
+		// Rejected with its siblings by the call site that rolled the
+		// attempt back.
+		return
+	}
+	if held {
+		// Still held in Permit, not rejected by the plugin: its wait timed out.
+		permitResults.WithLabelValues(permitTimeout).Inc()
+		msg := fmt.Sprintf("member %v timed out waiting for podGroup %v/%v to reach minAvailable", pod.Name, pod.Namespace, podGroupName)
+		s.events.podEvent(pod, v1.EventTypeWarning, reasonTimedOut, msg)
+		if err == nil {
+			s.events.groupEvent(pg, v1.EventTypeWarning, reasonTimedOut, msg)
+		}
+	}
+	if !held && s.gangs.waiting(key) == 0 || err == nil && s.formed(key, pg) {
+		// No quorum is being gathered: the member was admitted with its
+		// siblings, alone in its group or as an extra of a formed gang, and
+		// fails to bind on its own.
+		return
+	}
+
+	msg := fmt.Sprintf("podGroup %v/%v is rolled back because member %v is unreserved", pod.Namespace, podGroupName, pod.Name)
+	if !s.rejectWaitingPods(pod.Namespace, podGroupName, msg) {
+		// A sibling timing out at the same time rolled the attempt back.
+		return
+	}
+	s.queues.releaseGang(key)
+	if delay, started := s.backoff.fail(key); started {
+		groupBackoffs.WithLabelValues(pod.Namespace).Inc()
+		klog.V(3).Infof("podGroup %v backs off for %v", key, delay)
+	}
+	s.status.setAttempt(key, attemptFailed)
+	s.status.enqueue(pod.Namespace, podGroupName)
+	s.denials.Add(key, msg, denialTTL)
+}
+
+func (s *Sample) Permit(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (*framework.Status, time.Duration) {
+	podGroupName := s.groupName(pod)
+	if podGroupName == "" {
+		return framework.NewStatus(framework.Success, ""), 0
+	}
+	pg, err := s.getPodGroup(pod.Namespace, podGroupName)
+	if apierrors.IsNotFound(err) {
+		klog.Errorf("podgroup %v not found in %v", podGroupName, pod.Namespace)
+		permitResults.WithLabelValues(permitError).Inc()
+		return framework.NewStatus(framework.Error, "podgroup not found, please create podgroup or configmap first"), 0
+	}
+	if err != nil {
+		permitResults.WithLabelValues(permitError).Inc()
+		return framework.NewStatus(framework.Error, err.Error()), 0
+	}
+
+	namespace := pod.Namespace
+	key := namespace + "/" + podGroupName
+
+	ma := pg.minAvailable
+	if ma <= 1 && len(pg.minRoles) == 0 {
+		// Counted until it is bound, for maxMember.
+		s.gangs.admit(key, pod.Name)
+		s.reclaims.Remove(key)
+		s.queues.releaseGang(key)
+		permitResults.WithLabelValues(permitAllow).Inc()
+		return framework.NewStatus(framework.Success, ""), 0
+	}
+
+	// Members pulling their images or whose binding is in flight count as
+	// much as running ones, terminating members do not count at all.
+	scheduled := s.gangs.counts(key).scheduled(s.countSucceeded())
+	waiting := s.gangs.waiting(key)
+
+	if waiting == 0 && s.formed(key, pg) {
+		// The gang is already formed, e.g. it scales up: the member is one of
+		// the extras scheduled as they fit, up to maxMember.
+		klog.V(3).Infof("podGroup %v/%v already reached minAvailable(%d), allow the extra member %v: scheduled(%d)",
+			namespace, podGroupName, ma, pod.Name, scheduled)
+		s.gangs.admit(key, pod.Name)
+		s.status.enqueue(namespace, podGroupName)
+		permitResults.WithLabelValues(permitAllow).Inc()
+		return framework.NewStatus(framework.Success, ""), 0
+	}
+
+	current := scheduled + waiting + 1
+
+	// The members of each role holding a node, this one included.
+	var roles map[string]roleCounts
+	var unmet []string
+	role := s.role(pod)
+	holdingOf := func(r string, c roleCounts) int {
+		n := c.scheduled(s.countSucceeded()) + c.waiting
+		if r == role {
+			n++
+		}
+		return n
+	}
+	if len(pg.minRoles) > 0 {
+		roles = s.gangs.roleCounts(key, s.args.RoleLabelKey)
+		unmet = unmetRoles(pg, roles, holdingOf)
+	}
+
+	if current < ma || len(unmet) > 0 {
+		var msg string
+		if current < ma {
+			msg = fmt.Sprintf("The count of podGroup %v/%v/%v is not up to minAvailable(%d) in Permit: scheduled(%d), waiting(%d)",
+				pod.Namespace, podGroupName, pod.Name, ma, scheduled, waiting)
+		} else {
+			msg = fmt.Sprintf("The roles %v of podGroup %v/%v/%v are not up to their minimums in Permit: scheduled(%d), waiting(%d)",
+				unmet, pod.Namespace, podGroupName, pod.Name, scheduled, waiting)
+		}
+		if roles != nil {
+			msg += ", roles " + formatRoles(pg, roles, holdingOf)
+		}
 		klog.V(3).Info(msg)
-		return framework.NewStatus(framework.Wait, msg), time.Duration(sts) * time.Second
+		s.gangs.setWaiting(key, pod.Name, pg.scheduleTimeout)
+		s.status.setAttempt(key, attemptNone)
+		s.status.enqueue(namespace, podGroupName)
+		permitResults.WithLabelValues(permitWait).Inc()
+		s.events.podEvent(pod, v1.EventTypeNormal, reasonWaitingForQuorum, msg)
+		return framework.NewStatus(framework.Wait, msg), pg.scheduleTimeout
 	}
 
-	klog.V(3).Infof("The count of podGroup %v/%v/%v is up to minAvailable(%d) in Permit: running(%d), waiting(%d)",
-		pod.Namespace, podGroupName, pod.Name, ma, running, waiting)
+	klog.V(3).Infof("The count of podGroup %v/%v/%v is up to minAvailable(%d) in Permit: scheduled(%d), waiting(%d)",
+		pod.Namespace, podGroupName, pod.Name, ma, scheduled, waiting)
+	// Admitted before the siblings are allowed, so that the Unreserve of a
+	// sibling failing to bind right away does not roll the gang back.
+	if waited, held := s.gangs.admit(key, pod.Name); held {
+		admissionDuration.Observe(waited.Seconds())
+	}
+	s.status.setAttempt(key, attemptAdmitted)
+	msg := fmt.Sprintf("podGroup %v/%v reached minAvailable(%d): scheduled(%d), admitted(%d)",
+		namespace, podGroupName, ma, scheduled, waiting+1)
+	if roles != nil {
+		msg += ", roles " + formatRoles(pg, roles, holdingOf)
+	}
 	s.handle.IterateOverWaitingPods(func(waitingPod framework.WaitingPod) {
-		if waitingPod.GetPod().Namespace == namespace && waitingPod.GetPod().Labels[PodGroupName] == podGroupName {
+		if waitingPod.GetPod().Namespace == namespace && s.groupName(waitingPod.GetPod()) == podGroupName {
 			klog.V(3).Infof("Permit allows the pod: %v/%v", podGroupName, waitingPod.GetPod().Name)
 			waitingPod.Allow(s.Name())
+			s.events.podEvent(waitingPod.GetPod(), v1.EventTypeNormal, reasonQuorumReached, msg)
 		}
 	})
+	s.events.podEvent(pod, v1.EventTypeNormal, reasonQuorumReached, msg)
+	s.events.groupEvent(pg, v1.EventTypeNormal, reasonQuorumReached, msg)
+	s.backoff.reset(key)
+	s.reclaims.Remove(key)
+	s.queues.releaseGang(key)
+	s.status.enqueue(namespace, podGroupName)
+	permitResults.WithLabelValues(permitAllow).Inc()
 
 	return framework.NewStatus(framework.Success, ""), 0
 }
+
+// PostBind marks pod as borrowing when it was admitted beyond the min of its
+// queue, see markBorrowed.
+func (s *Sample) PostBind(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) {
+	s.markBorrowed(ctx, state, pod)
+}
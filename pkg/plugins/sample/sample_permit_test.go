@@ -18,14 +18,19 @@ package sample
 
 import (
 	"context"
+	"testing"
+
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	clientv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/kubernetes/pkg/scheduler/apis/config"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	framework_rt "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
-	"testing"
+
+	pluginconfig "github.com/FFFFFaraway/gang-scheduler/pkg/apis/config"
 )
 
 const (
@@ -61,6 +66,9 @@ func newFrameworkWithQueueSortAndBind(r framework_rt.Registry, pl *config.Plugin
 		Plugins:       plugins,
 		PluginConfig:  plc,
 	}
+	// The scheduler always has a nominator, give one to the frameworks of the
+	// tests that do not bring theirs.
+	opts = append([]framework_rt.Option{framework_rt.WithPodNominator(newFakePodNominator())}, opts...)
 	return framework_rt.NewFramework(r, profile, opts...)
 }
 
@@ -97,7 +105,7 @@ func TestPermit(t *testing.T) {
 			cfgPls := &config.Plugins{Permit: config.PluginSet{}}
 			if err := registry.Register(Name,
 				func(rt runtime.Object, handle framework.Handle) (framework.Plugin, error) {
-					return &Sample{handle: handle, podLister: &fakePodLister{}, cmLister: &fakeConfigMapLister{}}, nil
+					return newFakeSample(handle), nil
 				}); err != nil {
 				t.Fatalf("fail to register filter plugin (%s)", Name)
 			}
@@ -124,3 +132,198 @@ func TestPermit(t *testing.T) {
 		})
 	}
 }
+
+func TestPermitMemberAccounting(t *testing.T) {
+	member := func(name, nodeName string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{PodGroupName: "pg1"}},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	tests := []struct {
+		name             string
+		members          []*corev1.Pod
+		admitted         []string
+		succeededMembers string
+		expected         framework.Code
+	}{
+		{
+			name:     "pending members",
+			members:  []*corev1.Pod{member("pod1", "", corev1.PodPending), member("pod2", "", corev1.PodPending)},
+			expected: framework.Wait,
+		},
+		{
+			name:     "bound members pulling images",
+			members:  []*corev1.Pod{member("pod1", "node1", corev1.PodPending), member("pod2", "node1", corev1.PodPending)},
+			expected: framework.Success,
+		},
+		{
+			name:     "running members",
+			members:  []*corev1.Pod{member("pod1", "node1", corev1.PodRunning), member("pod2", "node1", corev1.PodRunning)},
+			expected: framework.Success,
+		},
+		{
+			name:     "assumed members whose binding is in flight",
+			members:  []*corev1.Pod{member("pod1", "", corev1.PodPending), member("pod2", "", corev1.PodPending)},
+			admitted: []string{"pod1", "pod2"},
+			expected: framework.Success,
+		},
+		{
+			name:     "bound and running members",
+			members:  []*corev1.Pod{member("pod1", "node1", corev1.PodPending), member("pod2", "node1", corev1.PodRunning)},
+			expected: framework.Success,
+		},
+		{
+			name:             "succeeded members counted",
+			members:          []*corev1.Pod{member("pod1", "node1", corev1.PodSucceeded), member("pod2", "node1", corev1.PodSucceeded)},
+			succeededMembers: pluginconfig.SucceededMembersCount,
+			expected:         framework.Success,
+		},
+		{
+			name:             "succeeded members ignored",
+			members:          []*corev1.Pod{member("pod1", "node1", corev1.PodSucceeded), member("pod2", "node1", corev1.PodSucceeded)},
+			succeededMembers: pluginconfig.SucceededMembersIgnore,
+			expected:         framework.Wait,
+		},
+		{
+			name:     "failed members",
+			members:  []*corev1.Pod{member("pod1", "node1", corev1.PodFailed), member("pod2", "node1", corev1.PodFailed)},
+			expected: framework.Wait,
+		},
+		{
+			name:     "terminating members",
+			members:  terminating(member("pod1", "node1", corev1.PodRunning), member("pod2", "node1", corev1.PodRunning)),
+			expected: framework.Wait,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newFrameworkWithQueueSortAndBind(framework_rt.Registry{}, &config.Plugins{}, emptyArgs,
+				framework_rt.WithSnapshotSharedLister(&fakeSharedLister{}))
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+			s := newFakeSample(f)
+			s.gangs = newGangManager()
+			if tt.succeededMembers != "" {
+				s.args.SucceededMembers = tt.succeededMembers
+			}
+			addPods(s, tt.members...)
+			for _, name := range tt.admitted {
+				s.gangs.admit("/pg1", name)
+			}
+
+			pod := member("pod3", "", corev1.PodPending)
+			if got, _ := s.Permit(context.TODO(), nil, pod, "node1"); got.Code() != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got.Code())
+			}
+		})
+	}
+}
+
+func TestElasticGang(t *testing.T) {
+	node := newNodeInfo("node1", corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")})
+	f, err := newFrameworkWithQueueSortAndBind(framework_rt.Registry{}, &config.Plugins{}, emptyArgs,
+		framework_rt.WithSnapshotSharedLister(&fakeSharedLister{nodeInfos: []*framework.NodeInfo{node}}))
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+	s := newFakeSample(f)
+	s.cmLister = clientv1.NewConfigMapLister(newIndexer(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "elastic", Namespace: "ns"}, Data: map[string]string{minAvailable: "2", maxMember: "3"}},
+	))
+	members := pendingMembers("elastic", "pod1", "pod2", "pod3", "pod4", "pod5")
+	for _, p := range members {
+		p.Namespace = "ns"
+	}
+	addPods(s, members[:4]...)
+	preFilter := func(pod *corev1.Pod, expected framework.Code) {
+		t.Helper()
+		if got := s.PreFilter(context.TODO(), framework.NewCycleState(), pod); got.Code() != expected {
+			t.Errorf("expected %v in PreFilter of %v, got %v: %v", expected, pod.Name, got.Code(), got.Message())
+		}
+	}
+	permit := func(pod *corev1.Pod, expected framework.Code) {
+		t.Helper()
+		if got, _ := s.Permit(context.TODO(), nil, pod, "node1"); got.Code() != expected {
+			t.Errorf("expected %v in Permit of %v, got %v: %v", expected, pod.Name, got.Code(), got.Message())
+		}
+	}
+
+	// minAvailable members wait for each other.
+	preFilter(members[0], framework.Success)
+	permit(members[0], framework.Wait)
+	preFilter(members[1], framework.Success)
+	permit(members[1], framework.Success)
+	// The extras do not wait, up to maxMember.
+	preFilter(members[2], framework.Success)
+	permit(members[2], framework.Success)
+	preFilter(members[3], framework.UnschedulableAndUnresolvable)
+
+	// A member leaving makes room for another one, without a new quorum.
+	s.gangs.deletePod(members[2])
+	preFilter(members[3], framework.Success)
+	permit(members[3], framework.Success)
+
+	// Members of a running gang scale it up the same way.
+	for i, p := range members[:2] {
+		bound := p.DeepCopy()
+		bound.Spec.NodeName = "node1"
+		bound.Status.Phase = corev1.PodRunning
+		members[i] = bound
+	}
+	addPods(s, members[0], members[1])
+	s.gangs.unreserve("ns/elastic", members[3].Name)
+	scaleUp := members[4]
+	addPods(s, scaleUp)
+	preFilter(scaleUp, framework.Success)
+	permit(scaleUp, framework.Success)
+}
+
+func TestRoleGang(t *testing.T) {
+	node := newNodeInfo("node1", corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")})
+	f, err := newFrameworkWithQueueSortAndBind(framework_rt.Registry{}, &config.Plugins{}, emptyArgs,
+		framework_rt.WithSnapshotSharedLister(&fakeSharedLister{nodeInfos: []*framework.NodeInfo{node}}))
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+	s := newFakeSample(f)
+	s.cmLister = clientv1.NewConfigMapLister(newIndexer(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "ps-job", Namespace: "ns"}, Data: map[string]string{minAvailable: "5", minRoles: "ps=1,worker=4"}},
+	))
+	workers := pendingMembers("ps-job", "worker-0", "worker-1", "worker-2", "worker-3", "worker-4")
+	ps := pendingMembers("ps-job", "ps-0")[0]
+	for _, p := range append(workers, ps) {
+		p.Namespace = "ns"
+		p.Labels[PodGroupRole] = "worker"
+	}
+	ps.Labels[PodGroupRole] = "ps"
+	addPods(s, workers...)
+
+	// Enough members, but no parameter server yet.
+	if got := s.PreFilter(context.TODO(), framework.NewCycleState(), workers[0]); got.Code() != framework.UnschedulableAndUnresolvable {
+		t.Errorf("expected the workers to be unschedulable without ps, got %v: %v", got.Code(), got.Message())
+	}
+	addPods(s, ps)
+	if got := s.PreFilter(context.TODO(), framework.NewCycleState(), workers[0]); got.Code() != framework.Success {
+		t.Errorf("expected the workers to be schedulable with ps, got %v: %v", got.Code(), got.Message())
+	}
+
+	// 5 workers reach minAvailable, but not the minimum of ps.
+	for _, p := range workers {
+		if got, _ := s.Permit(context.TODO(), nil, p, "node1"); got.Code() != framework.Wait {
+			t.Errorf("expected %v to wait, got %v: %v", p.Name, got.Code(), got.Message())
+		}
+	}
+	roles := s.gangs.roleCounts("ns/ps-job", PodGroupRole)
+	if roles["worker"].waiting != 5 || roles["ps"].waiting != 0 {
+		t.Errorf("expected 5 waiting workers and no waiting ps, got %+v", roles)
+	}
+	if got, _ := s.Permit(context.TODO(), nil, ps, "node1"); got.Code() != framework.Success {
+		t.Errorf("expected ps to complete the gang, got %v: %v", got.Code(), got.Message())
+	}
+	if waiting := s.gangs.waiting("ns/ps-job"); waiting != 0 {
+		t.Errorf("expected the gang to be admitted, got %d waiting", waiting)
+	}
+}
@@ -0,0 +1,119 @@
+package sample
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	framework_rt "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+)
+
+func TestPostFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		waiting []*corev1.Pod
+		pod     *corev1.Pod
+		// failedFilter is set when pod passed PreFilter and failed Filter.
+		failedFilter bool
+		rejected     []bool
+		denied       bool
+	}{
+		{
+			name: "common pod not belongs any podGroup",
+			waiting: []*corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{PodGroupName: "pg1"}, UID: types.UID("pod1")}},
+			},
+			pod:          &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2", UID: types.UID("pod2")}},
+			failedFilter: true,
+			rejected:     []bool{false},
+		},
+		{
+			name: "siblings of an unschedulable member are rejected",
+			waiting: []*corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{PodGroupName: "pg1"}, UID: types.UID("pod1")}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Labels: map[string]string{PodGroupName: "pg1"}, UID: types.UID("pod2")}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod3", Labels: map[string]string{PodGroupName: "pg4"}, UID: types.UID("pod3")}},
+			},
+			pod:          &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod4", Labels: map[string]string{PodGroupName: "pg1"}, UID: types.UID("pod4")}},
+			failedFilter: true,
+			rejected:     []bool{true, true, false},
+			denied:       true,
+		},
+		{
+			name: "siblings of a member denied in PreFilter are left waiting",
+			waiting: []*corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{PodGroupName: "pg1"}, UID: types.UID("pod1")}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Labels: map[string]string{PodGroupName: "pg1"}, UID: types.UID("pod2")}},
+			},
+			pod:      &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod4", Labels: map[string]string{PodGroupName: "pg1"}, UID: types.UID("pod4")}},
+			rejected: []bool{false, false},
+		},
+	}
+	Name := "SomethingHere"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s *Sample
+			registry := framework_rt.Registry{}
+			cfgPls := &config.Plugins{}
+			if err := registry.Register(Name,
+				func(rt runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+					s = newFakeSample(handle)
+					return s, nil
+				}); err != nil {
+				t.Fatalf("fail to register postfilter plugin (%s)", Name)
+			}
+			cfgPls.PostFilter.Enabled = append(cfgPls.PostFilter.Enabled, config.Plugin{Name: Name})
+			// A waiting pod is only released by the plugin that holds it.
+			cfgPls.Permit.Enabled = append(cfgPls.Permit.Enabled, config.Plugin{Name: waitPlugin})
+			registry[waitPlugin] = newWaitPlugin
+
+			f, err := newFrameworkWithQueueSortAndBind(registry, cfgPls, emptyArgs, framework_rt.WithSnapshotSharedLister(&fakeSharedLister{}))
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+			for _, p := range tt.waiting {
+				if got := f.RunPermitPlugins(context.TODO(), nil, p, "node1"); got.Code() != framework.Wait {
+					t.Fatalf("expected %v to wait, got %v", p.Name, got.Code())
+				}
+				// The plugin holds the members of a group in Permit.
+				if group := p.Labels[PodGroupName]; group != "" {
+					s.gangs.setWaiting(p.Namespace+"/"+group, p.Name, time.Minute)
+				}
+			}
+
+			state := framework.NewCycleState()
+			if tt.failedFilter {
+				state.Write(preFilterStateKey, &preFilterState{passed: true})
+			}
+			_, got := f.RunPostFilterPlugins(context.TODO(), state, tt.pod, nil)
+			if got.Code() != framework.Unschedulable {
+				t.Errorf("expected %v, got %v", framework.Unschedulable, got.Code())
+			}
+			// A rejected gang is denied for a while, apart from its capacity
+			// simulation.
+			key := tt.pod.Namespace + "/" + tt.pod.Labels[PodGroupName]
+			if _, denied := s.denials.Get(key); denied != tt.denied {
+				t.Errorf("expected %v denied %v, got %v", key, tt.denied, denied)
+			}
+			if _, cached := s.capacityCache.Get(key); cached {
+				t.Errorf("expected no capacity simulation cached for %v", key)
+			}
+
+			for i, p := range tt.waiting {
+				if !tt.rejected[i] {
+					// Release the pods that are still held, a rejected one reports its rejection first.
+					f.GetWaitingPod(p.UID).Allow(waitPlugin)
+				}
+				if rejected := !f.WaitOnPermit(context.TODO(), p).IsSuccess(); rejected != tt.rejected[i] {
+					t.Errorf("expected %v rejected %v, got %v", p.Name, tt.rejected[i], rejected)
+				}
+			}
+		})
+	}
+}
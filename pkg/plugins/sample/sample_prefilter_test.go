@@ -0,0 +1,111 @@
+package sample
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	framework_rt "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+)
+
+func TestPreFilter(t *testing.T) {
+	node := newNodeInfo("node1", corev1.ResourceList{corev1.ResourcePods: resource.MustParse("2")})
+	tests := []struct {
+		name         string
+		pod          *corev1.Pod
+		members      []*corev1.Pod
+		nodeInfos    []*framework.NodeInfo
+		backingOff   bool
+		requireGroup bool
+		expected     framework.Code
+	}{
+		{
+			name:     "common pod not belongs any podGroup",
+			pod:      &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}},
+			expected: framework.Success,
+		},
+		{
+			name:         "common pod when a group is required",
+			pod:          &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}},
+			requireGroup: true,
+			expected:     framework.UnschedulableAndUnresolvable,
+		},
+		{
+			name:     "fewer members created than minAvailable",
+			pod:      &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{PodGroupName: "pg1"}}},
+			expected: framework.UnschedulableAndUnresolvable,
+		},
+		{
+			name:      "enough members created",
+			pod:       &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{PodGroupName: "pg1"}}},
+			members:   pendingMembers("pg1", "pg1-2", "pg1-3"),
+			nodeInfos: []*framework.NodeInfo{node, node.Clone()},
+			expected:  framework.Success,
+		},
+		{
+			name:      "enough members created but they do not fit",
+			pod:       &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{PodGroupName: "pg1"}}},
+			members:   pendingMembers("pg1", "pg1-2", "pg1-3"),
+			nodeInfos: []*framework.NodeInfo{node},
+			expected:  framework.Unschedulable,
+		},
+		{
+			name:     "extra member of a bound podGroup",
+			pod:      &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{PodGroupName: "pg2"}}},
+			expected: framework.Success,
+		},
+		{
+			name:      "terminating members do not count",
+			pod:       &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{PodGroupName: "pg1"}}},
+			members:   terminating(pendingMembers("pg1", "pg1-2", "pg1-3")...),
+			nodeInfos: []*framework.NodeInfo{node, node.Clone()},
+			expected:  framework.UnschedulableAndUnresolvable,
+		},
+		{
+			name:       "podGroup backing off",
+			pod:        &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{PodGroupName: "pg2"}}},
+			nodeInfos:  []*framework.NodeInfo{node, node.Clone()},
+			backingOff: true,
+			expected:   framework.UnschedulableAndUnresolvable,
+		},
+		{
+			name:     "podGroup not found",
+			pod:      &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{PodGroupName: "pg3"}}},
+			expected: framework.UnschedulableAndUnresolvable,
+		},
+	}
+	Name := "SomethingHere"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := framework_rt.Registry{}
+			cfgPls := &config.Plugins{PreFilter: config.PluginSet{}}
+			if err := registry.Register(Name,
+				func(rt runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+					s := newFakeSample(handle)
+					s.args.RequireGroup = tt.requireGroup
+					addPods(s, tt.members...)
+					if tt.backingOff {
+						s.backoff.fail("/pg2")
+					}
+					return s, nil
+				}); err != nil {
+				t.Fatalf("fail to register prefilter plugin (%s)", Name)
+			}
+			cfgPls.PreFilter.Enabled = append(cfgPls.PreFilter.Enabled, config.Plugin{Name: Name})
+
+			f, err := newFrameworkWithQueueSortAndBind(registry, cfgPls, emptyArgs, framework_rt.WithSnapshotSharedLister(&fakeSharedLister{nodeInfos: tt.nodeInfos}))
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+
+			if got := f.RunPreFilterPlugins(context.TODO(), framework.NewCycleState(), tt.pod); got.Code() != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got.Code())
+			}
+		})
+	}
+}
@@ -17,12 +17,20 @@ limitations under the License.
 package sample
 
 import (
-	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
 	"testing"
+	"testing/quick"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientv1 "k8s.io/client-go/listers/core/v1"
+	schedulinglisters "k8s.io/client-go/listers/scheduling/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
 func TestLess(t *testing.T) {
@@ -345,10 +353,221 @@ func TestLess(t *testing.T) {
 		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
-			coscheduling := &Sample{}
+			coscheduling := &Sample{args: defaultArgs(), gangs: newGangManager()}
 			if got := coscheduling.Less(tt.p1, tt.p2); got != tt.expected {
 				t.Errorf("expected %v, got %v", tt.expected, got)
 			}
 		})
 	}
 }
+
+// queuedPods is a random set of queued pods, spread over a few groups whose
+// creation times collide, for property based tests of Less.
+type queuedPods []*framework.QueuedPodInfo
+
+var (
+	sortBase       = time.Date(2022, 8, 14, 0, 0, 0, 0, time.UTC)
+	sortNamespaces = []string{"namespace1", "namespace2"}
+	// The last group has no ConfigMap, its members sort as if not in a group.
+	sortGroups = []string{"pg1", "pg2", "pg3", "pg4", "missing"}
+	// Groups without a class, or with one that does not exist, take the default priority.
+	sortClasses = []string{"high", "", "unknown"}
+)
+
+// Generate implements quick.Generator.
+func (queuedPods) Generate(r *rand.Rand, size int) reflect.Value {
+	// Transitivity is checked over every triple, keep the sets small.
+	if size > 20 {
+		size = 20
+	}
+	pods := make(queuedPods, r.Intn(size+1))
+	for i := range pods {
+		priority := int32(r.Intn(3))
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("pod%d", i),
+				Namespace: sortNamespaces[r.Intn(len(sortNamespaces))],
+			},
+			Spec: corev1.PodSpec{Priority: &priority},
+		}
+		if g := r.Intn(len(sortGroups) + 1); g < len(sortGroups) {
+			pod.Labels = map[string]string{PodGroupName: sortGroups[g]}
+		}
+		pods[i] = &framework.QueuedPodInfo{
+			PodInfo:                 &framework.PodInfo{Pod: pod},
+			InitialAttemptTimestamp: sortBase.Add(time.Duration(r.Intn(3)) * time.Second),
+		}
+	}
+	return reflect.ValueOf(pods)
+}
+
+func newSortSample() *Sample {
+	var cms []interface{}
+	for _, ns := range sortNamespaces {
+		for i, g := range sortGroups[:len(sortGroups)-1] {
+			cms = append(cms, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      g,
+					Namespace: ns,
+					// Two groups per creation time, so that ties are common.
+					CreationTimestamp: metav1.NewTime(sortBase.Add(time.Duration(i/2) * time.Second)),
+				},
+				Data: map[string]string{minAvailable: "2", priorityClassName: sortClasses[i%len(sortClasses)]},
+			})
+		}
+	}
+	pcs := newIndexer(
+		&schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "high"}, Value: 2},
+		&schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "default"}, Value: 1, GlobalDefault: true},
+	)
+	return &Sample{
+		args:     defaultArgs(),
+		gangs:    newGangManager(),
+		cmLister: clientv1.NewConfigMapLister(newIndexer(cms...)),
+		pcLister: schedulinglisters.NewPriorityClassLister(pcs),
+	}
+}
+
+func TestLessIsStrictWeakOrdering(t *testing.T) {
+	s := newSortSample()
+	less := s.Less
+	property := func(pods queuedPods) bool {
+		for _, a := range pods {
+			if less(a, a) {
+				t.Logf("irreflexivity: %v < %v", a.Pod.Name, a.Pod.Name)
+				return false
+			}
+			for _, b := range pods {
+				if less(a, b) && less(b, a) {
+					t.Logf("asymmetry: %v and %v", a.Pod.Name, b.Pod.Name)
+					return false
+				}
+				for _, c := range pods {
+					if less(a, b) && less(b, c) && !less(a, c) {
+						t.Logf("transitivity: %v < %v < %v", a.Pod.Name, b.Pod.Name, c.Pod.Name)
+						return false
+					}
+					incomparable := func(x, y *framework.QueuedPodInfo) bool { return !less(x, y) && !less(y, x) }
+					if incomparable(a, b) && incomparable(b, c) && !incomparable(a, c) {
+						t.Logf("transitivity of incomparability: %v ~ %v ~ %v", a.Pod.Name, b.Pod.Name, c.Pod.Name)
+						return false
+					}
+				}
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 200}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLessKeepsGroupsContiguous(t *testing.T) {
+	s := newSortSample()
+	property := func(pods queuedPods) bool {
+		sort.SliceStable(pods, func(i, j int) bool { return s.Less(pods[i], pods[j]) })
+		seen := map[string]bool{}
+		last := ""
+		for _, p := range pods {
+			key := s.queueSortKey(p.Pod).group
+			if key != last && seen[key] && key != "" {
+				t.Logf("members of %v are split in the queue", key)
+				return false
+			}
+			seen[key] = true
+			last = key
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestQueueSortKeyIsStable checks that registering members does not change
+// the sort key of the pods already queued, which would break the heap of the
+// scheduling queue.
+func TestQueueSortKeyIsStable(t *testing.T) {
+	property := func(pods queuedPods) bool {
+		s := newSortSample()
+		keys := map[*framework.QueuedPodInfo]queueSortKey{}
+		for _, p := range pods {
+			addPods(s, p.Pod)
+			keys[p] = s.queueSortKey(p.Pod)
+			for queued, key := range keys {
+				if got := s.queueSortKey(queued.Pod); got != key {
+					t.Logf("sort key of %v changed from %+v to %+v once %v is registered", queued.Pod.Name, key, got, p.Pod.Name)
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 200}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLessGroupPriority(t *testing.T) {
+	var low, medium, high = int32(0), int32(50), int32(100)
+	member := func(name, group string, priority *int32) *framework.QueuedPodInfo {
+		return &framework.QueuedPodInfo{
+			PodInfo: &framework.PodInfo{Pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "namespace1", Labels: map[string]string{PodGroupName: group}},
+				Spec:       corev1.PodSpec{Priority: priority},
+			}},
+			InitialAttemptTimestamp: sortBase,
+		}
+	}
+	for _, tt := range []struct {
+		name string
+		p1   *framework.QueuedPodInfo
+		p2   *framework.QueuedPodInfo
+		// others are members known besides p1 and p2.
+		others   []*framework.QueuedPodInfo
+		expected bool
+	}{
+		{
+			name:     "newer high priority group goes ahead of older default priority group",
+			p1:       member("pod1", "pg3", &high),
+			p2:       member("pod2", "pg1", &low),
+			expected: false,
+		},
+		{
+			name:     "member priority is replaced by the group priority",
+			p1:       member("pod1", "pg1", &high),
+			p2:       &framework.QueuedPodInfo{PodInfo: &framework.PodInfo{Pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}, Spec: corev1.PodSpec{Priority: &medium}}}},
+			expected: false,
+		},
+		{
+			name:     "regular pod with a higher priority goes ahead of a group",
+			p1:       &framework.QueuedPodInfo{PodInfo: &framework.PodInfo{Pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}, Spec: corev1.PodSpec{Priority: &high}}}},
+			p2:       member("pod2", "pg1", &high),
+			expected: true,
+		},
+		{
+			name:     "group without a class takes the priority of its first member",
+			p1:       member("pod1", "pg2", &high),
+			p2:       &framework.QueuedPodInfo{PodInfo: &framework.PodInfo{Pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}, Spec: corev1.PodSpec{Priority: &medium}}}},
+			others:   []*framework.QueuedPodInfo{member("pod3", "pg2", &low)},
+			expected: true,
+		},
+		{
+			name:     "later members do not change the priority of a group without a class",
+			p1:       member("pod1", "pg2", &low),
+			p2:       &framework.QueuedPodInfo{PodInfo: &framework.PodInfo{Pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}, Spec: corev1.PodSpec{Priority: &medium}}}},
+			others:   []*framework.QueuedPodInfo{member("pod3", "pg2", &high)},
+			expected: false,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSortSample()
+			for _, p := range append([]*framework.QueuedPodInfo{tt.p1, tt.p2}, tt.others...) {
+				addPods(s, p.Pod)
+			}
+			if got := s.Less(tt.p1, tt.p2); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package sample
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	framework_rt "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+)
+
+func TestUnreserve(t *testing.T) {
+	tests := []struct {
+		name    string
+		waiting []*corev1.Pod
+		pod     *corev1.Pod
+		// held are the members of the group of pod held in Permit by the
+		// plugin.
+		held     []string
+		admitted bool
+		rejected []bool
+		attempt  attempt
+	}{
+		{
+			name: "common pod not belongs any podGroup",
+			waiting: []*corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{PodGroupName: "pg1"}, UID: types.UID("pod1")}},
+			},
+			pod:      &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2", UID: types.UID("pod2")}},
+			rejected: []bool{false},
+			attempt:  attemptNone,
+		},
+		{
+			name: "timed out member rolls back the gang",
+			waiting: []*corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{PodGroupName: "pg1"}, UID: types.UID("pod1")}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Labels: map[string]string{PodGroupName: "pg4"}, UID: types.UID("pod2")}},
+			},
+			pod:      &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod3", Labels: map[string]string{PodGroupName: "pg1"}, UID: types.UID("pod3")}},
+			held:     []string{"pod1", "pod3"},
+			rejected: []bool{true, false},
+			attempt:  attemptFailed,
+		},
+		{
+			name:    "member failing to bind while no quorum is gathered",
+			pod:     &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod3", Labels: map[string]string{PodGroupName: "pg1"}, UID: types.UID("pod3")}},
+			attempt: attemptNone,
+		},
+		{
+			// pg2 is formed by its 3 running members, Permit allows the extra
+			// member without an attempt.
+			name:    "extra member of a formed gang fails to bind",
+			pod:     &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pg2-4", Labels: map[string]string{PodGroupName: "pg2"}, UID: types.UID("pg2-4")}},
+			attempt: attemptNone,
+		},
+		{
+			name: "member of an admitted gang fails to bind",
+			waiting: []*corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{PodGroupName: "pg1"}, UID: types.UID("pod1")}},
+			},
+			pod:      &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod3", Labels: map[string]string{PodGroupName: "pg1"}, UID: types.UID("pod3")}},
+			admitted: true,
+			rejected: []bool{false},
+			attempt:  attemptAdmitted,
+		},
+	}
+	Name := "SomethingHere"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := "/pg1"
+			if group := tt.pod.Labels[PodGroupName]; group != "" {
+				key = "/" + group
+			}
+			var sample *Sample
+			registry := framework_rt.Registry{}
+			cfgPls := &config.Plugins{}
+			if err := registry.Register(Name,
+				func(rt runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+					sample = newFakeSample(handle)
+					for _, name := range tt.held {
+						sample.gangs.setWaiting(key, name, time.Minute)
+					}
+					if tt.admitted {
+						for _, p := range tt.waiting {
+							sample.gangs.admit(key, p.Name)
+						}
+						sample.gangs.admit(key, tt.pod.Name)
+						sample.status.setAttempt(key, attemptAdmitted)
+					}
+					return sample, nil
+				}); err != nil {
+				t.Fatalf("fail to register reserve plugin (%s)", Name)
+			}
+			cfgPls.Reserve.Enabled = append(cfgPls.Reserve.Enabled, config.Plugin{Name: Name})
+			cfgPls.Permit.Enabled = append(cfgPls.Permit.Enabled, config.Plugin{Name: waitPlugin})
+			registry[waitPlugin] = newWaitPlugin
+
+			f, err := newFrameworkWithQueueSortAndBind(registry, cfgPls, emptyArgs, framework_rt.WithSnapshotSharedLister(&fakeSharedLister{}))
+			if err != nil {
+				t.Fatalf("fail to create framework: %s", err)
+			}
+			for _, p := range tt.waiting {
+				if got := f.RunPermitPlugins(context.TODO(), nil, p, "node1"); got.Code() != framework.Wait {
+					t.Fatalf("expected %v to wait, got %v", p.Name, got.Code())
+				}
+			}
+
+			f.RunReservePluginsUnreserve(context.TODO(), framework.NewCycleState(), tt.pod, "node1")
+
+			for i, p := range tt.waiting {
+				if !tt.rejected[i] {
+					f.GetWaitingPod(p.UID).Allow(waitPlugin)
+				}
+				if rejected := !f.WaitOnPermit(context.TODO(), p).IsSuccess(); rejected != tt.rejected[i] {
+					t.Errorf("expected %v rejected %v, got %v", p.Name, tt.rejected[i], rejected)
+				}
+			}
+			if got := sample.status.getAttempt(key); got != tt.attempt {
+				t.Errorf("expected attempt %v, got %v", tt.attempt, got)
+			}
+			if backingOff := sample.backoff.remaining(key) > 0; backingOff != (tt.attempt == attemptFailed) {
+				t.Errorf("expected backing off %v, got %v", tt.attempt == attemptFailed, backingOff)
+			}
+		})
+	}
+}
+
+// TestUnreserveRejectedSiblings checks that the members rejected by the
+// plugin do not roll their gang back again, however their Unreserve, which
+// runs asynchronously, interleaves with the rejection of their siblings and
+// whatever the plugin holds meanwhile.
+func TestUnreserveRejectedSiblings(t *testing.T) {
+	var s *Sample
+	registry := framework_rt.Registry{}
+	cfgPls := &config.Plugins{}
+	if err := registry.Register(Name,
+		func(rt runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+			s = newFakeSample(handle)
+			return s, nil
+		}); err != nil {
+		t.Fatalf("fail to register plugin (%s)", Name)
+	}
+	cfgPls.PostFilter.Enabled = append(cfgPls.PostFilter.Enabled, config.Plugin{Name: Name})
+	cfgPls.Reserve.Enabled = append(cfgPls.Reserve.Enabled, config.Plugin{Name: Name})
+	cfgPls.Permit.Enabled = append(cfgPls.Permit.Enabled, config.Plugin{Name: waitPlugin})
+	registry[waitPlugin] = newWaitPlugin
+
+	f, err := newFrameworkWithQueueSortAndBind(registry, cfgPls, emptyArgs, framework_rt.WithSnapshotSharedLister(&fakeSharedLister{}))
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+	var waiting []*corev1.Pod
+	for i := 1; i <= 8; i++ {
+		name := fmt.Sprintf("pod%d", i)
+		waiting = append(waiting, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{PodGroupName: "pg1"}, UID: types.UID(name)}})
+	}
+	for _, p := range waiting {
+		if got := f.RunPermitPlugins(context.TODO(), nil, p, "node1"); got.Code() != framework.Wait {
+			t.Fatalf("expected %v to wait, got %v", p.Name, got.Code())
+		}
+		s.gangs.setWaiting("/pg1", p.Name, time.Minute)
+	}
+	// The Permit of another member is returning, the framework does not hold
+	// it yet.
+	s.gangs.setWaiting("/pg1", "pod9", time.Minute)
+
+	// Every member unreserves as soon as it is rejected, as the binding
+	// cycle of the scheduler does.
+	var wg sync.WaitGroup
+	for _, p := range waiting {
+		wg.Add(1)
+		go func(p *corev1.Pod) {
+			defer wg.Done()
+			if f.WaitOnPermit(context.TODO(), p).IsSuccess() {
+				t.Errorf("expected %v to be rejected", p.Name)
+				return
+			}
+			f.RunReservePluginsUnreserve(context.TODO(), framework.NewCycleState(), p, "node1")
+		}(p)
+	}
+
+	state := framework.NewCycleState()
+	state.Write(preFilterStateKey, &preFilterState{passed: true})
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod0", Labels: map[string]string{PodGroupName: "pg1"}, UID: types.UID("pod0")}}
+	f.RunPostFilterPlugins(context.TODO(), state, pod, nil)
+	wg.Wait()
+
+	// PostFilter denies the gang, it does not back off.
+	if got := s.status.getAttempt("/pg1"); got == attemptFailed {
+		t.Errorf("expected no failed attempt, got %v", got)
+	}
+	if remaining := s.backoff.remaining("/pg1"); remaining > 0 {
+		t.Errorf("expected no backoff, got %v", remaining)
+	}
+	if waiting := s.gangs.waiting("/pg1"); waiting != 0 {
+		t.Errorf("expected no member held, got %v", waiting)
+	}
+}
@@ -0,0 +1,102 @@
+package sample
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	framework_rt "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+)
+
+// zoneNode returns the NodeInfo of a node in zone, in no zone if it is empty.
+func zoneNode(name, zone string, pods ...*corev1.Pod) *framework.NodeInfo {
+	ni := newNodeInfo(name, corev1.ResourceList{}, pods...)
+	if zone != "" {
+		ni.Node().Labels = map[string]string{"topology.kubernetes.io/zone": zone}
+	}
+	return ni
+}
+
+func TestScore(t *testing.T) {
+	member := func(name, group, nodeName string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Labels: map[string]string{PodGroupName: group}},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+		}
+	}
+	finished := member("pack-2", "pack", "node3")
+	finished.Status.Phase = corev1.PodSucceeded
+	nodeInfos := []*framework.NodeInfo{
+		zoneNode("node1", "a", member("pack-1", "pack", "node1"), member("spread-1", "spread", "node1")),
+		zoneNode("node2", "a"),
+		zoneNode("node3", "b", finished, member("other-1", "other", "node3")),
+		zoneNode("node4", ""),
+	}
+	f, err := newFrameworkWithQueueSortAndBind(framework_rt.Registry{}, nil, emptyArgs,
+		framework_rt.WithSnapshotSharedLister(&fakeSharedLister{nodeInfos: nodeInfos}))
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+	s := newFakeSample(f)
+	s.cmLister = clientv1.NewConfigMapLister(newIndexer(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "pack", Namespace: "ns"}, Data: map[string]string{minAvailable: "3"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "spread", Namespace: "ns"}, Data: map[string]string{minAvailable: "3", placement: "Spread"}},
+	))
+
+	for _, tt := range []struct {
+		name     string
+		pod      *corev1.Pod
+		expected map[string]int64
+	}{
+		{
+			name:     "pack next to the siblings",
+			pod:      member("pack-3", "pack", ""),
+			expected: map[string]int64{"node1": 100, "node2": 100, "node3": 0, "node4": 0},
+		},
+		{
+			name:     "spread away from the siblings",
+			pod:      member("spread-2", "spread", ""),
+			expected: map[string]int64{"node1": 0, "node2": 0, "node3": 100, "node4": 100},
+		},
+		{
+			name:     "pod not in a group",
+			pod:      &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns"}},
+			expected: map[string]int64{"node1": 0, "node2": 0, "node3": 0, "node4": 0},
+		},
+		{
+			name:     "group not found",
+			pod:      member("missing-1", "missing", ""),
+			expected: map[string]int64{"node1": 0, "node2": 0, "node3": 0, "node4": 0},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.TODO()
+			state := framework.NewCycleState()
+			if status := s.PreScore(ctx, state, tt.pod, nil); !status.IsSuccess() {
+				t.Fatalf("unexpected PreScore status %v", status)
+			}
+			var scores framework.NodeScoreList
+			for _, ni := range nodeInfos {
+				score, status := s.Score(ctx, state, tt.pod, ni.Node().Name)
+				if !status.IsSuccess() {
+					t.Fatalf("unexpected Score status %v", status)
+				}
+				scores = append(scores, framework.NodeScore{Name: ni.Node().Name, Score: score})
+			}
+			if status := s.ScoreExtensions().NormalizeScore(ctx, state, tt.pod, scores); !status.IsSuccess() {
+				t.Fatalf("unexpected NormalizeScore status %v", status)
+			}
+			got := map[string]int64{}
+			for _, score := range scores {
+				got[score.Name] = score.Score
+			}
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("unexpected scores (-want,+got): %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package sample
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	pluginhelper "k8s.io/kubernetes/pkg/scheduler/framework/plugins/helper"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+)
+
+const topologyStateKey framework.StateKey = Name + "/topology"
+
+// topologyState is the number of siblings of the pod being scheduled in each
+// topology domain, computed once per scheduling cycle in PreScore.
+type topologyState struct {
+	// siblings are counted by domain, see topologyDomain.
+	siblings  map[string]int
+	placement v1alpha1.PodGroupPlacement
+}
+
+// Clone implements framework.StateData. The state is read only.
+func (t *topologyState) Clone() framework.StateData {
+	return t
+}
+
+// topologyDomain returns the topology domain of node: the value of its
+// TopologyKey label, or the node itself when it has none.
+func (s *Sample) topologyDomain(node *v1.Node) string {
+	if value, exist := node.Labels[s.args.TopologyKey]; exist {
+		return "domain/" + value
+	}
+	return "node/" + node.Name
+}
+
+// PreScore counts the siblings of pod in every topology domain: the members
+// of its group bound to a node, being bound, or waiting in Permit on their
+// reserved node, as they all are on the snapshot.
+func (s *Sample) PreScore(ctx context.Context, state *framework.CycleState, pod *v1.Pod, _ []*v1.Node) *framework.Status {
+	podGroupName := s.groupName(pod)
+	if podGroupName == "" {
+		return framework.NewStatus(framework.Success, "")
+	}
+	pg, err := s.getPodGroup(pod.Namespace, podGroupName)
+	if err != nil {
+		// Nothing to score the nodes with, PreFilter reported why.
+		return framework.NewStatus(framework.Success, "")
+	}
+	nodeInfos, err := s.handle.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		return framework.NewStatus(framework.Error, err.Error())
+	}
+	siblings := map[string]int{}
+	for _, ni := range nodeInfos {
+		if ni.Node() == nil {
+			continue
+		}
+		for _, pi := range ni.Pods {
+			p := pi.Pod
+			if p.Namespace == pod.Namespace && p.Name != pod.Name && p.DeletionTimestamp == nil &&
+				p.Status.Phase != v1.PodSucceeded && p.Status.Phase != v1.PodFailed && s.groupName(p) == podGroupName {
+				siblings[s.topologyDomain(ni.Node())]++
+			}
+		}
+	}
+	state.Write(topologyStateKey, &topologyState{siblings: siblings, placement: pg.placement})
+	return framework.NewStatus(framework.Success, "")
+}
+
+// Score returns the number of siblings of pod in the topology domain of the
+// node, NormalizeScore turns it into a score favouring the domains holding
+// the most siblings, or the fewest for the groups spreading their members.
+func (s *Sample) Score(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (int64, *framework.Status) {
+	t, err := readTopologyState(state)
+	if err != nil {
+		return 0, framework.NewStatus(framework.Success, "")
+	}
+	ni, err := s.handle.SnapshotSharedLister().NodeInfos().Get(nodeName)
+	if err != nil {
+		return 0, framework.NewStatus(framework.Error, fmt.Sprintf("getting node %q from snapshot: %v", nodeName, err))
+	}
+	if ni.Node() == nil {
+		return 0, framework.NewStatus(framework.Success, "")
+	}
+	return int64(t.siblings[s.topologyDomain(ni.Node())]), framework.NewStatus(framework.Success, "")
+}
+
+func (s *Sample) ScoreExtensions() framework.ScoreExtensions {
+	return s
+}
+
+// NormalizeScore scales the scores to [0, MaxNodeScore], reversed for the
+// groups spreading their members.
+func (s *Sample) NormalizeScore(ctx context.Context, state *framework.CycleState, pod *v1.Pod, scores framework.NodeScoreList) *framework.Status {
+	t, err := readTopologyState(state)
+	if err != nil {
+		return framework.NewStatus(framework.Success, "")
+	}
+	return pluginhelper.DefaultNormalizeScore(framework.MaxNodeScore, t.placement == v1alpha1.PodGroupPlacementSpread, scores)
+}
+
+// readTopologyState returns the state written by PreScore, an error if it
+// wrote none because the pod is not in a valid group.
+func readTopologyState(state *framework.CycleState) (*topologyState, error) {
+	data, err := state.Read(topologyStateKey)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := data.(*topologyState)
+	if !ok {
+		return nil, fmt.Errorf("%+v is not a *topologyState", data)
+	}
+	return t, nil
+}
@@ -0,0 +1,328 @@
+package sample
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	clientv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config"
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+	pglisters "github.com/FFFFFaraway/gang-scheduler/pkg/generated/listers/scheduling/v1alpha1"
+)
+
+const (
+	podGroupSourceName  = "PodGroup"
+	configMapSourceName = "ConfigMap"
+	// cacheSyncTimeout is how long the plugin waits for the informers of the
+	// group sources it starts to sync.
+	cacheSyncTimeout = time.Minute
+)
+
+// groupSource is a kind of object declaring groups.
+type groupSource interface {
+	// name identifies the source in podGroup.source and in logs.
+	name() string
+	// get returns the group namespace/name, a NotFound error if the source
+	// does not declare it.
+	get(namespace, name string) (*podGroup, error)
+}
+
+// groupSources returns the sources groups are looked up in, in order: the
+// PodGroup of this scheduler, the PodGroups of other schedulers, then the
+// legacy ConfigMap.
+func (s *Sample) groupSources() []groupSource {
+	var sources []groupSource
+	if s.pgLister != nil {
+		sources = append(sources, podGroupSource{lister: s.pgLister})
+	}
+	sources = append(sources, s.foreignSources...)
+	return append(sources, configMapSource{lister: s.cmLister})
+}
+
+// podGroupSource reads the PodGroups of this scheduler.
+type podGroupSource struct {
+	lister pglisters.PodGroupLister
+}
+
+func (podGroupSource) name() string {
+	return podGroupSourceName
+}
+
+func (src podGroupSource) get(namespace, name string) (*podGroup, error) {
+	pg, err := src.lister.PodGroups(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	var sts int64
+	if pg.Spec.ScheduleTimeoutSeconds != nil {
+		sts = int64(*pg.Spec.ScheduleTimeoutSeconds)
+	}
+	var mm int
+	if pg.Spec.MaxMember != nil {
+		mm = int(*pg.Spec.MaxMember)
+	}
+	var mr map[string]int
+	if len(pg.Spec.MinRoles) > 0 {
+		mr = make(map[string]int, len(pg.Spec.MinRoles))
+		for role, min := range pg.Spec.MinRoles {
+			mr[role] = int(min)
+		}
+	}
+	return &podGroup{
+		namespace:            pg.Namespace,
+		name:                 pg.Name,
+		minAvailable:         int(pg.Spec.MinAvailable),
+		maxMember:            mm,
+		minRoles:             mr,
+		placement:            pg.Spec.Placement,
+		requiredTopologyKeys: pg.Spec.RequiredTopologyKeys,
+		timeoutSeconds:       sts,
+		creationTime:         pg.CreationTimestamp.Time,
+		priorityClassName:    pg.Spec.PriorityClassName,
+		ref: &v1.ObjectReference{
+			APIVersion: v1alpha1.SchemeGroupVersion.String(),
+			Kind:       "PodGroup",
+			Namespace:  pg.Namespace,
+			Name:       pg.Name,
+			UID:        pg.UID,
+		},
+	}, nil
+}
+
+// configMapSource reads the legacy ConfigMaps holding minAvailable.
+type configMapSource struct {
+	lister clientv1.ConfigMapLister
+}
+
+func (configMapSource) name() string {
+	return configMapSourceName
+}
+
+func (src configMapSource) get(namespace, name string) (*podGroup, error) {
+	cm, err := src.lister.ConfigMaps(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	ref := &v1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Namespace:  cm.Namespace,
+		Name:       cm.Name,
+		UID:        cm.UID,
+	}
+	maStr, exist := cm.Data[minAvailable]
+	if !exist {
+		return nil, &invalidGroupError{ref: ref, msg: fmt.Sprintf("minAvailable field not found in podgroup configmap %v/%v", namespace, name)}
+	}
+	ma, err := v1alpha1.ParseMinAvailable(maStr)
+	if err != nil {
+		return nil, &invalidGroupError{ref: ref, msg: fmt.Sprintf("minAvailable of podgroup configmap %v/%v is not an integer: %q", namespace, name, maStr)}
+	}
+	var mm int
+	if mmStr, exist := cm.Data[maxMember]; exist {
+		mm, err = strconv.Atoi(mmStr)
+		if err != nil {
+			return nil, &invalidGroupError{ref: ref, msg: fmt.Sprintf("maxMember of podgroup configmap %v/%v is not an integer: %q", namespace, name, mmStr)}
+		}
+	}
+	var mr map[string]int
+	if mrStr, exist := cm.Data[minRoles]; exist {
+		roles, err := v1alpha1.ParseMinRoles(mrStr)
+		if err != nil {
+			return nil, &invalidGroupError{ref: ref, msg: fmt.Sprintf("minRoles of podgroup configmap %v/%v is invalid: %v", namespace, name, err)}
+		}
+		mr = make(map[string]int, len(roles))
+		for role, min := range roles {
+			mr[role] = int(min)
+		}
+	}
+	var rtk []string
+	for _, k := range strings.Split(cm.Data[requiredTopologyKeys], ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			rtk = append(rtk, k)
+		}
+	}
+	var sts int64
+	if stsStr, exist := cm.Data[scheduleTimeoutSeconds]; exist {
+		sts, err = strconv.ParseInt(stsStr, 10, 64)
+		if err != nil {
+			return nil, &invalidGroupError{ref: ref, msg: fmt.Sprintf("scheduleTimeoutSeconds of podgroup configmap %v/%v is not an integer: %q", namespace, name, stsStr)}
+		}
+	}
+	return &podGroup{
+		namespace:            namespace,
+		name:                 name,
+		minAvailable:         int(ma),
+		maxMember:            mm,
+		minRoles:             mr,
+		placement:            v1alpha1.PodGroupPlacement(cm.Data[placement]),
+		requiredTopologyKeys: rtk,
+		timeoutSeconds:       sts,
+		creationTime:         cm.CreationTimestamp.Time,
+		priorityClassName:    cm.Data[priorityClassName],
+		ref:                  ref,
+	}, nil
+}
+
+// foreignPodGroup is the PodGroup CRD of another scheduler.
+type foreignPodGroup struct {
+	gvr schema.GroupVersionResource
+	// timeoutField is the path of scheduleTimeoutSeconds in the object, nil
+	// if the CRD has none.
+	timeoutField []string
+	// priorityClassField is the path of priorityClassName in the object, nil
+	// if the CRD has none.
+	priorityClassField []string
+}
+
+// foreignPodGroups are the PodGroup CRDs that can be enabled with
+// SampleArgs.ForeignPodGroups. Both declare the size of the group in
+// spec.minMember.
+var foreignPodGroups = map[string]foreignPodGroup{
+	config.VolcanoPodGroups: {
+		gvr:                schema.GroupVersionResource{Group: "scheduling.volcano.sh", Version: "v1beta1", Resource: "podgroups"},
+		priorityClassField: []string{"spec", "priorityClassName"},
+	},
+	config.SchedulerPluginsPodGroups: {
+		gvr:          schema.GroupVersionResource{Group: "scheduling.x-k8s.io", Version: "v1alpha1", Resource: "podgroups"},
+		timeoutField: []string{"spec", "scheduleTimeoutSeconds"},
+	},
+}
+
+// dynamicSource reads a foreign PodGroup CRD through a dynamic informer, so
+// that the types of other schedulers need not be vendored.
+type dynamicSource struct {
+	sourceName string
+	kind       foreignPodGroup
+	lister     cache.GenericLister
+}
+
+func (src dynamicSource) name() string {
+	return src.sourceName
+}
+
+func (src dynamicSource) get(namespace, name string) (*podGroup, error) {
+	obj, err := src.lister.ByNamespace(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T in %v", obj, src.kind.gvr)
+	}
+	ref := &v1.ObjectReference{
+		APIVersion: u.GetAPIVersion(),
+		Kind:       u.GetKind(),
+		Namespace:  u.GetNamespace(),
+		Name:       u.GetName(),
+		UID:        u.GetUID(),
+	}
+	minMember, _, err := unstructured.NestedInt64(u.Object, "spec", "minMember")
+	if err != nil {
+		return nil, &invalidGroupError{ref: ref, msg: err.Error()}
+	}
+	pg := &podGroup{
+		namespace:    namespace,
+		name:         name,
+		minAvailable: int(minMember),
+		creationTime: u.GetCreationTimestamp().Time,
+		ref:          ref,
+	}
+	if src.kind.timeoutField != nil {
+		if pg.timeoutSeconds, _, err = unstructured.NestedInt64(u.Object, src.kind.timeoutField...); err != nil {
+			return nil, &invalidGroupError{ref: ref, msg: err.Error()}
+		}
+	}
+	if src.kind.priorityClassField != nil {
+		if pg.priorityClassName, _, err = unstructured.NestedString(u.Object, src.kind.priorityClassField...); err != nil {
+			return nil, &invalidGroupError{ref: ref, msg: err.Error()}
+		}
+	}
+	return pg, nil
+}
+
+// newForeignSources returns the sources reading the PodGroup CRDs of other
+// schedulers named in names, in the same order. The CRDs that are not served
+// by the apiserver are skipped, failing to discover them is an error. handler
+// is notified of their changes.
+func newForeignSources(names []string, client dynamic.Interface, discoveryClient discovery.DiscoveryInterface,
+	handler cache.ResourceEventHandler, stopCh <-chan struct{}) ([]groupSource, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+	var sources []groupSource
+	for _, name := range names {
+		kind := foreignPodGroups[name]
+		served, err := resourceServed(discoveryClient, kind.gvr)
+		if err != nil {
+			return nil, err
+		}
+		if !served {
+			klog.Warningf("%v is not served by the apiserver, ignoring %v podgroups", kind.gvr, name)
+			continue
+		}
+		informer := factory.ForResource(kind.gvr)
+		informer.Informer().AddEventHandler(handler)
+		sources = append(sources, dynamicSource{
+			sourceName: name,
+			kind:       kind,
+			lister:     informer.Lister(),
+		})
+	}
+	factory.Start(stopCh)
+	for gvr, synced := range factory.WaitForCacheSync(syncStop(stopCh)) {
+		if !synced {
+			return nil, fmt.Errorf("timed out waiting for the cache of %v to sync", gvr)
+		}
+	}
+	return sources, nil
+}
+
+// syncStop returns the channel to wait for the informers started with stopCh
+// to sync with: it is closed with stopCh, or after cacheSyncTimeout, so that
+// an apiserver failing to serve a CRD fails the start of the scheduler
+// instead of hanging it.
+func syncStop(stopCh <-chan struct{}) <-chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		defer close(ch)
+		select {
+		case <-stopCh:
+		case <-time.After(cacheSyncTimeout):
+		}
+	}()
+	return ch
+}
+
+// resourceServed tells whether the apiserver serves gvr. Only a group
+// version the apiserver does not know makes it not served, any other error
+// is returned so that an apiserver failing to answer is not taken for a
+// missing CRD.
+func resourceServed(client discovery.DiscoveryInterface, gvr schema.GroupVersionResource) (bool, error) {
+	resources, err := client.ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to discover %v: %w", gvr, err)
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == gvr.Resource {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,208 @@
+package sample
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	clientv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/config"
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+	pglisters "github.com/FFFFFaraway/gang-scheduler/pkg/generated/listers/scheduling/v1alpha1"
+)
+
+func newForeignPodGroup(gvr schema.GroupVersionResource, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	u.SetAPIVersion(gvr.GroupVersion().String())
+	u.SetKind("PodGroup")
+	u.SetNamespace("ns")
+	u.SetName(name)
+	return u
+}
+
+// fakeDiscovery answers with the apiserver's NotFound for the group versions
+// it does not serve, unlike FakeDiscovery. err, when set, is returned for
+// every group version instead.
+type fakeDiscovery struct {
+	*fakediscovery.FakeDiscovery
+	err error
+}
+
+func (d *fakeDiscovery) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	for _, resources := range d.Resources {
+		if resources.GroupVersion == groupVersion {
+			return resources, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Group: groupVersion}, "")
+}
+
+// newFakeForeignSources returns the sources of names backed by the dynamic
+// fake client holding objs, as if the apiserver served the CRDs in served.
+func newFakeForeignSources(t *testing.T, names []string, served []string, objs ...runtime.Object) []groupSource {
+	listKinds := map[schema.GroupVersionResource]string{}
+	for _, kind := range foreignPodGroups {
+		listKinds[kind.gvr] = "PodGroupList"
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objs...)
+
+	discoveryClient := &fakeDiscovery{FakeDiscovery: fake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)}
+	for _, name := range served {
+		gvr := foreignPodGroups[name].gvr
+		discoveryClient.Resources = append(discoveryClient.Resources, &metav1.APIResourceList{
+			GroupVersion: gvr.GroupVersion().String(),
+			APIResources: []metav1.APIResource{{Name: gvr.Resource, Namespaced: true, Kind: "PodGroup"}},
+		})
+	}
+
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	sources, err := newForeignSources(names, client, discoveryClient, cache.ResourceEventHandlerFuncs{}, stopCh)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return sources
+}
+
+func TestResourceServed(t *testing.T) {
+	gvr := v1alpha1.SchemeGroupVersion.WithResource("queues")
+	for _, tt := range []struct {
+		name      string
+		resources []metav1.APIResource
+		err       error
+		expected  bool
+		wantErr   bool
+	}{
+		{name: "served", resources: []metav1.APIResource{{Name: "podgroups"}, {Name: "queues"}}, expected: true},
+		{name: "group version served without the resource", resources: []metav1.APIResource{{Name: "podgroups"}}},
+		{name: "group version not served"},
+		{name: "apiserver failing", resources: []metav1.APIResource{{Name: "queues"}},
+			err: apierrors.NewServiceUnavailable("try again"), wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeDiscovery{FakeDiscovery: fake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery), err: tt.err}
+			if tt.resources != nil {
+				client.Resources = []*metav1.APIResourceList{{GroupVersion: gvr.GroupVersion().String(), APIResources: tt.resources}}
+			}
+			served, err := resourceServed(client, gvr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if served != tt.expected {
+				t.Errorf("expected served %v, got %v", tt.expected, served)
+			}
+		})
+	}
+}
+
+func TestForeignSources(t *testing.T) {
+	volcano := foreignPodGroups[config.VolcanoPodGroups].gvr
+	schedulerPlugins := foreignPodGroups[config.SchedulerPluginsPodGroups].gvr
+	objs := []runtime.Object{
+		newForeignPodGroup(volcano, "volcano", map[string]interface{}{
+			"minMember":         int64(4),
+			"priorityClassName": "high",
+			"queue":             "default",
+		}),
+		newForeignPodGroup(schedulerPlugins, "scheduler-plugins", map[string]interface{}{
+			"minMember":              int64(3),
+			"scheduleTimeoutSeconds": int64(20),
+		}),
+		newForeignPodGroup(volcano, "both", map[string]interface{}{"minMember": int64(5)}),
+		newForeignPodGroup(schedulerPlugins, "both", map[string]interface{}{"minMember": int64(6)}),
+		newForeignPodGroup(schedulerPlugins, "own", map[string]interface{}{"minMember": int64(7)}),
+		newForeignPodGroup(schedulerPlugins, "cm", map[string]interface{}{"minMember": int64(8)}),
+	}
+	pgs := newIndexer(&v1alpha1.PodGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "own", Namespace: "ns"},
+		Spec:       v1alpha1.PodGroupSpec{MinAvailable: 2},
+	})
+	cms := newIndexer(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "ns"},
+		Data:       map[string]string{minAvailable: "9"},
+	})
+
+	for _, tt := range []struct {
+		name              string
+		foreign           []string
+		served            []string
+		group             string
+		minAvailable      int
+		scheduleTimeout   time.Duration
+		priorityClassName string
+		source            string
+		notFound          bool
+	}{
+		{
+			name:    "volcano podgroup",
+			foreign: []string{config.VolcanoPodGroups}, served: []string{config.VolcanoPodGroups},
+			group: "volcano", minAvailable: 4, scheduleTimeout: 10 * time.Second, priorityClassName: "high", source: config.VolcanoPodGroups,
+		},
+		{
+			name:    "scheduler-plugins podgroup",
+			foreign: []string{config.SchedulerPluginsPodGroups}, served: []string{config.SchedulerPluginsPodGroups},
+			group: "scheduler-plugins", minAvailable: 3, scheduleTimeout: 20 * time.Second, source: config.SchedulerPluginsPodGroups,
+		},
+		{
+			name:    "foreign podgroups in the configured order",
+			foreign: []string{config.SchedulerPluginsPodGroups, config.VolcanoPodGroups}, served: []string{config.VolcanoPodGroups, config.SchedulerPluginsPodGroups},
+			group: "both", minAvailable: 6, scheduleTimeout: 10 * time.Second, source: config.SchedulerPluginsPodGroups,
+		},
+		{
+			name:    "own podgroup wins over foreign ones",
+			foreign: []string{config.SchedulerPluginsPodGroups}, served: []string{config.SchedulerPluginsPodGroups},
+			group: "own", minAvailable: 2, scheduleTimeout: 10 * time.Second, source: podGroupSourceName,
+		},
+		{
+			name:    "foreign podgroup wins over configmap",
+			foreign: []string{config.SchedulerPluginsPodGroups}, served: []string{config.SchedulerPluginsPodGroups},
+			group: "cm", minAvailable: 8, scheduleTimeout: 10 * time.Second, source: config.SchedulerPluginsPodGroups,
+		},
+		{
+			name:  "foreign podgroups are not read unless enabled",
+			group: "volcano", notFound: true,
+		},
+		{
+			name:    "foreign podgroups are skipped when the crd is not served",
+			foreign: []string{config.VolcanoPodGroups},
+			group:   "volcano", notFound: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Sample{
+				args:           defaultArgs(),
+				gangs:          newGangManager(),
+				pgLister:       pglisters.NewPodGroupLister(pgs),
+				cmLister:       clientv1.NewConfigMapLister(cms),
+				foreignSources: newFakeForeignSources(t, tt.foreign, tt.served, objs...),
+			}
+			pg, err := s.getPodGroup("ns", tt.group)
+			if tt.notFound {
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("expected not found, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if pg.minAvailable != tt.minAvailable || pg.scheduleTimeout != tt.scheduleTimeout ||
+				pg.priorityClassName != tt.priorityClassName || pg.source != tt.source {
+				t.Errorf("unexpected podgroup %+v", pg)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package sample
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+	pgclientset "github.com/FFFFFaraway/gang-scheduler/pkg/generated/clientset/versioned"
+)
+
+// statusUpdater writes the phase and member counts of every group back to
+// the object declaring it. Syncs are queued by group key, so that a burst of
+// pod events only results in one write.
+type statusUpdater struct {
+	queue    workqueue.RateLimitingInterface
+	pgClient pgclientset.Interface
+
+	lock sync.Mutex
+	// attempts records the outcome of the current scheduling attempt of a
+	// group, for what can not be told from the member pods alone.
+	attempts map[string]attempt
+}
+
+// attempt is the outcome of the current scheduling attempt of a group.
+type attempt int
+
+const (
+	attemptNone attempt = iota
+	// attemptAdmitted means the quorum was reached in Permit, and the members
+	// are being bound.
+	attemptAdmitted
+	// attemptFailed means the waiting members were rolled back before the
+	// quorum was reached.
+	attemptFailed
+)
+
+func newStatusUpdater(pgClient pgclientset.Interface) *statusUpdater {
+	return &statusUpdater{
+		queue:    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "podgroup-status"),
+		pgClient: pgClient,
+		attempts: map[string]attempt{},
+	}
+}
+
+func (u *statusUpdater) enqueue(namespace, name string) {
+	u.queue.Add(namespace + "/" + name)
+}
+
+func (u *statusUpdater) setAttempt(key string, a attempt) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if a == attemptNone {
+		delete(u.attempts, key)
+	} else {
+		u.attempts[key] = a
+	}
+}
+
+func (u *statusUpdater) getAttempt(key string) attempt {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return u.attempts[key]
+}
+
+// podGroupStatusCounts are the member counts a phase is computed from.
+type podGroupStatusCounts struct {
+	running, waiting, bound, succeeded, failed, total int
+	// roles are the counts of each role the group sets a minimum for, nil if
+	// it sets none.
+	roles map[string]v1alpha1.PodGroupRoleStatus
+}
+
+// groupPhase computes the phase of a group from its member counts, the
+// outcome of its current attempt and the phase it was last reported in.
+func groupPhase(minAvailable int, c podGroupStatusCounts, a attempt, last v1alpha1.PodGroupPhase) v1alpha1.PodGroupPhase {
+	switch {
+	case c.failed > 0 && c.total-c.failed < minAvailable:
+		return v1alpha1.PodGroupFailed
+	case c.running+c.succeeded >= minAvailable:
+		return v1alpha1.PodGroupRunning
+	case c.bound >= minAvailable || a == attemptAdmitted:
+		return v1alpha1.PodGroupScheduled
+	case c.waiting > 0:
+		return v1alpha1.PodGroupWaiting
+	case a == attemptFailed || last == v1alpha1.PodGroupWaiting || last == v1alpha1.PodGroupTimedOut:
+		// The waiting members left Permit without the quorum being reached.
+		return v1alpha1.PodGroupTimedOut
+	}
+	return v1alpha1.PodGroupPending
+}
+
+// addStatusEventHandlers feeds the status updater from the group informers,
+// pod events are fed by gangPodEventHandler.
+func (s *Sample) addStatusEventHandlers(pgInformer cache.SharedIndexInformer) {
+	groupHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    s.enqueuePodGroup,
+		UpdateFunc: func(_, obj interface{}) { s.enqueuePodGroup(obj) },
+	}
+	s.handle.SharedInformerFactory().Core().V1().ConfigMaps().Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			cm, ok := obj.(*v1.ConfigMap)
+			if !ok {
+				return false
+			}
+			_, exist := cm.Data[minAvailable]
+			return exist
+		},
+		Handler: groupHandler,
+	})
+	if pgInformer != nil {
+		pgInformer.AddEventHandler(groupHandler)
+	}
+}
+
+func (s *Sample) enqueuePodGroupOf(pod *v1.Pod) {
+	if pg := s.groupName(pod); pg != "" {
+		s.status.enqueue(pod.Namespace, pg)
+	}
+}
+
+func (s *Sample) enqueuePodGroup(obj interface{}) {
+	if m, err := meta.Accessor(obj); err == nil {
+		s.status.enqueue(m.GetNamespace(), m.GetName())
+	}
+}
+
+// runStatusUpdater processes queued groups until stopCh is closed.
+func (s *Sample) runStatusUpdater(stopCh <-chan struct{}) {
+	go wait.Until(func() {
+		for s.processNextStatus() {
+		}
+	}, time.Second, stopCh)
+	go func() {
+		<-stopCh
+		s.status.queue.ShutDown()
+	}()
+}
+
+func (s *Sample) processNextStatus() bool {
+	item, quit := s.status.queue.Get()
+	if quit {
+		return false
+	}
+	defer s.status.queue.Done(item)
+
+	key := item.(string)
+	if err := s.syncStatus(key); err != nil {
+		klog.V(3).Infof("failed to update status of podgroup %v: %v", key, err)
+		s.status.queue.AddRateLimited(key)
+		return true
+	}
+	s.status.queue.Forget(key)
+	return true
+}
+
+func (s *Sample) syncStatus(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	pg, err := s.getPodGroup(namespace, name)
+	if _, invalid := asInvalidGroup(err); apierrors.IsNotFound(err) || invalid {
+		// Retrying does not help, the group is synced again once fixed.
+		s.status.setAttempt(key, attemptNone)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// The status reports what the apiserver knows: members the scheduler
+	// only assumed are not bound yet, and terminating members are gone.
+	members := s.gangs.counts(key)
+	c := podGroupStatusCounts{
+		running:   members.running,
+		waiting:   s.gangs.waiting(key),
+		bound:     members.bound - members.assumed,
+		succeeded: members.succeeded,
+		failed:    members.failed,
+		total:     members.total - members.terminating,
+	}
+	if len(pg.minRoles) > 0 {
+		roles := s.gangs.roleCounts(key, s.args.RoleLabelKey)
+		c.roles = make(map[string]v1alpha1.PodGroupRoleStatus, len(pg.minRoles))
+		for role := range pg.minRoles {
+			rc := roles[role]
+			c.roles[role] = v1alpha1.PodGroupRoleStatus{Waiting: int32(rc.waiting), Bound: int32(rc.bound - rc.assumed)}
+		}
+	}
+
+	a := s.status.getAttempt(key)
+	if a == attemptAdmitted && c.bound >= pg.minAvailable {
+		// Binding finished, the counts speak for themselves from now on.
+		a = attemptNone
+		s.status.setAttempt(key, attemptNone)
+	}
+
+	switch pg.source {
+	case podGroupSourceName:
+		obj, err := s.pgLister.PodGroups(namespace).Get(name)
+		if err != nil {
+			return err
+		}
+		return s.updatePodGroupStatus(obj, pg, c, a)
+	case configMapSourceName:
+		cm, err := s.cmLister.ConfigMaps(namespace).Get(name)
+		if err != nil {
+			return err
+		}
+		return s.updateConfigMapStatus(cm, pg, c, a)
+	}
+	// The status of a foreign PodGroup belongs to its own scheduler.
+	return nil
+}
+
+func (s *Sample) updatePodGroupStatus(obj *v1alpha1.PodGroup, pg *podGroup, c podGroupStatusCounts, a attempt) error {
+	status := obj.Status.DeepCopy()
+	status.Phase = groupPhase(pg.minAvailable, c, a, obj.Status.Phase)
+	status.Running = int32(c.running)
+	status.Waiting = int32(c.waiting)
+	status.Bound = int32(c.bound)
+	status.Succeeded = int32(c.succeeded)
+	status.Failed = int32(c.failed)
+	status.Roles = c.roles
+	if status.Phase != obj.Status.Phase || status.LastTransitionTime == nil {
+		now := metav1.Now()
+		status.LastTransitionTime = &now
+	}
+	if apiequality.Semantic.DeepEqual(status, &obj.Status) {
+		return nil
+	}
+
+	updated := obj.DeepCopy()
+	updated.Status = *status
+	_, err := s.status.pgClient.SchedulingV1alpha1().PodGroups(obj.Namespace).UpdateStatus(context.TODO(), updated, metav1.UpdateOptions{})
+	return err
+}
+
+func (s *Sample) updateConfigMapStatus(cm *v1.ConfigMap, pg *podGroup, c podGroupStatusCounts, a attempt) error {
+	last := v1alpha1.PodGroupPhase(cm.Annotations[v1alpha1.PhaseAnnotation])
+	phase := groupPhase(pg.minAvailable, c, a, last)
+	annotations := map[string]string{
+		v1alpha1.PhaseAnnotation:     string(phase),
+		v1alpha1.RunningAnnotation:   strconv.Itoa(c.running),
+		v1alpha1.WaitingAnnotation:   strconv.Itoa(c.waiting),
+		v1alpha1.BoundAnnotation:     strconv.Itoa(c.bound),
+		v1alpha1.SucceededAnnotation: strconv.Itoa(c.succeeded),
+		v1alpha1.FailedAnnotation:    strconv.Itoa(c.failed),
+	}
+	if c.roles != nil {
+		roles, err := json.Marshal(c.roles)
+		if err != nil {
+			return err
+		}
+		annotations[v1alpha1.RolesAnnotation] = string(roles)
+	}
+	if phase != last || cm.Annotations[v1alpha1.LastTransitionTimeAnnotation] == "" {
+		annotations[v1alpha1.LastTransitionTimeAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	// Only the annotations that changed are patched, nothing when none did:
+	// the ConfigMap handlers see the patch too. The roles of a group that
+	// has none anymore are removed.
+	changed := map[string]interface{}{}
+	for k, v := range annotations {
+		if cm.Annotations[k] != v {
+			changed[k] = v
+		}
+	}
+	if _, exist := cm.Annotations[v1alpha1.RolesAnnotation]; exist && c.roles == nil {
+		changed[v1alpha1.RolesAnnotation] = nil
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": changed},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.handle.ClientSet().CoreV1().ConfigMaps(cm.Namespace).Patch(context.TODO(), cm.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
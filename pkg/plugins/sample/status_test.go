@@ -0,0 +1,132 @@
+package sample
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clientv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+	framework_rt "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+
+	"github.com/FFFFFaraway/gang-scheduler/pkg/apis/scheduling/v1alpha1"
+	pgfake "github.com/FFFFFaraway/gang-scheduler/pkg/generated/clientset/versioned/fake"
+	pglisters "github.com/FFFFFaraway/gang-scheduler/pkg/generated/listers/scheduling/v1alpha1"
+)
+
+func TestGroupPhase(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		counts   podGroupStatusCounts
+		attempt  attempt
+		last     v1alpha1.PodGroupPhase
+		expected v1alpha1.PodGroupPhase
+	}{
+		{name: "nothing happened yet", counts: podGroupStatusCounts{total: 3}, expected: v1alpha1.PodGroupPending},
+		{name: "members waiting", counts: podGroupStatusCounts{waiting: 2, total: 3}, last: v1alpha1.PodGroupPending, expected: v1alpha1.PodGroupWaiting},
+		{name: "admitted but not bound", counts: podGroupStatusCounts{total: 3}, attempt: attemptAdmitted, last: v1alpha1.PodGroupWaiting, expected: v1alpha1.PodGroupScheduled},
+		{name: "bound but not running", counts: podGroupStatusCounts{bound: 3, total: 3}, expected: v1alpha1.PodGroupScheduled},
+		{name: "running", counts: podGroupStatusCounts{running: 3, bound: 3, total: 3}, expected: v1alpha1.PodGroupRunning},
+		{name: "waiting members gave up", counts: podGroupStatusCounts{total: 3}, last: v1alpha1.PodGroupWaiting, expected: v1alpha1.PodGroupTimedOut},
+		{name: "waiting members rolled back", counts: podGroupStatusCounts{total: 3}, attempt: attemptFailed, last: v1alpha1.PodGroupPending, expected: v1alpha1.PodGroupTimedOut},
+		{name: "still timed out", counts: podGroupStatusCounts{total: 3}, last: v1alpha1.PodGroupTimedOut, expected: v1alpha1.PodGroupTimedOut},
+		{name: "too many failed", counts: podGroupStatusCounts{running: 1, failed: 1, bound: 3, total: 3}, expected: v1alpha1.PodGroupFailed},
+		{name: "failed but replaced", counts: podGroupStatusCounts{running: 3, failed: 1, bound: 4, total: 4}, expected: v1alpha1.PodGroupRunning},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := groupPhase(3, tt.counts, tt.attempt, tt.last); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSyncStatus(t *testing.T) {
+	pods := []*corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "cm-1", Namespace: "ns", Labels: map[string]string{PodGroupName: "cm"}},
+			Spec:       corev1.PodSpec{NodeName: "node1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "cm-2", Namespace: "ns", Labels: map[string]string{PodGroupName: "cm"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "crd-1", Namespace: "ns", Labels: map[string]string{PodGroupName: "crd", PodGroupRole: "worker"}},
+			Spec:       corev1.PodSpec{NodeName: "node1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "crd-2", Namespace: "ns", Labels: map[string]string{PodGroupName: "crd", PodGroupRole: "worker"}},
+			Spec:       corev1.PodSpec{NodeName: "node2"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	}
+	// The group had roles before.
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "ns", Annotations: map[string]string{v1alpha1.RolesAnnotation: `{"worker":{}}`}},
+		Data:       map[string]string{minAvailable: "2"},
+	}
+	pg := &v1alpha1.PodGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "crd", Namespace: "ns"},
+		Spec:       v1alpha1.PodGroupSpec{MinAvailable: 2, MinRoles: map[string]int32{"worker": 2}},
+	}
+	kubeClient := fake.NewSimpleClientset(cm)
+	pgClient := pgfake.NewSimpleClientset(pg)
+
+	f, err := newFrameworkWithQueueSortAndBind(framework_rt.Registry{}, &config.Plugins{}, emptyArgs,
+		framework_rt.WithClientSet(kubeClient), framework_rt.WithSnapshotSharedLister(&fakeSharedLister{}))
+	if err != nil {
+		t.Fatalf("fail to create framework: %s", err)
+	}
+	s := &Sample{
+		handle:   f,
+		args:     defaultArgs(),
+		gangs:    newGangManager(),
+		cmLister: clientv1.NewConfigMapLister(newIndexer(cm)),
+		pgLister: pglisters.NewPodGroupLister(newIndexer(pg)),
+		status:   newStatusUpdater(pgClient),
+	}
+	addPods(s, pods...)
+
+	if err := s.syncStatus("ns/cm"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gotCM, _ := kubeClient.CoreV1().ConfigMaps("ns").Get(context.TODO(), "cm", metav1.GetOptions{})
+	for k, v := range map[string]string{v1alpha1.PhaseAnnotation: "Pending", v1alpha1.RunningAnnotation: "1", v1alpha1.BoundAnnotation: "1", v1alpha1.WaitingAnnotation: "0"} {
+		if gotCM.Annotations[k] != v {
+			t.Errorf("expected annotation %v=%v, got %v", k, v, gotCM.Annotations[k])
+		}
+	}
+	if gotCM.Annotations[v1alpha1.LastTransitionTimeAnnotation] == "" {
+		t.Errorf("expected %v to be set", v1alpha1.LastTransitionTimeAnnotation)
+	}
+	if roles, exist := gotCM.Annotations[v1alpha1.RolesAnnotation]; exist {
+		t.Errorf("expected the roles to be removed, got %v", roles)
+	}
+	// Nothing is patched when nothing changed.
+	s.cmLister = clientv1.NewConfigMapLister(newIndexer(gotCM))
+	kubeClient.ClearActions()
+	if err := s.syncStatus("ns/cm"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actions := kubeClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected no patch, got %v", actions)
+	}
+
+	if err := s.syncStatus("ns/crd"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gotPG, _ := pgClient.SchedulingV1alpha1().PodGroups("ns").Get(context.TODO(), "crd", metav1.GetOptions{})
+	if gotPG.Status.Phase != v1alpha1.PodGroupRunning || gotPG.Status.Running != 2 || gotPG.Status.Bound != 2 {
+		t.Errorf("unexpected status %+v", gotPG.Status)
+	}
+	if roles := gotPG.Status.Roles; len(roles) != 1 || roles["worker"].Bound != 2 || roles["worker"].Waiting != 0 {
+		t.Errorf("unexpected roles %+v", roles)
+	}
+	if gotPG.Status.LastTransitionTime == nil {
+		t.Errorf("expected lastTransitionTime to be set")
+	}
+}
@@ -0,0 +1,198 @@
+package sample
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// requiredDomain is a topology domain a group requiring one is placed in: the
+// nodes sharing the values of its required topology keys, down to the level
+// the domain was chosen at.
+type requiredDomain struct {
+	keys   []string
+	values []string
+}
+
+// domainOf returns the domain of node at the level of keys, false if the node
+// lacks one of them.
+func domainOf(node *v1.Node, keys []string) (*requiredDomain, bool) {
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value, exist := node.Labels[key]
+		if !exist {
+			return nil, false
+		}
+		values = append(values, value)
+	}
+	return &requiredDomain{keys: keys, values: values}, true
+}
+
+// has tells whether node is in the domain.
+func (d *requiredDomain) has(node *v1.Node) bool {
+	for i, key := range d.keys {
+		if value, exist := node.Labels[key]; !exist || value != d.values[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// of tells whether the domain was chosen for keys, which the group may have
+// changed since.
+func (d *requiredDomain) of(keys []string) bool {
+	if len(d.keys) > len(keys) {
+		return false
+	}
+	for i, key := range d.keys {
+		if keys[i] != key {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the domain as key=value pairs, e.g.
+// "topology.kubernetes.io/zone=a,example.com/rack=r1".
+func (d *requiredDomain) String() string {
+	pairs := make([]string, 0, len(d.keys))
+	for i, key := range d.keys {
+		pairs = append(pairs, key+"="+d.values[i])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// validateRequiredTopologyKeys returns why the requiredTopologyKeys of pg
+// are invalid, empty if they are valid.
+func validateRequiredTopologyKeys(pg *podGroup) string {
+	seen := sets.NewString()
+	for _, key := range pg.requiredTopologyKeys {
+		if msgs := validation.IsQualifiedName(key); len(msgs) > 0 {
+			return fmt.Sprintf("requiredTopologyKeys of podGroup %v/%v has an invalid key %q: %v",
+				pg.namespace, pg.name, key, strings.Join(msgs, ", "))
+		}
+		if seen.Has(key) {
+			return fmt.Sprintf("requiredTopologyKeys of podGroup %v/%v has the key %q twice", pg.namespace, pg.name, key)
+		}
+		seen.Insert(key)
+	}
+	return ""
+}
+
+// placeInDomain places the members of pg requiring a topology domain the way
+// simulateGang does, on the nodes of a single domain. The domain chosen
+// before is kept while members hold a node in it. Otherwise the narrowest
+// domain holding the siblings already placed and the remaining members is
+// chosen and remembered for the group.
+func (s *Sample) placeInDomain(nodeInfos []*framework.NodeInfo, pod *v1.Pod, pg *podGroup) *capacityState {
+	key := pg.namespace + "/" + pg.name
+	members := s.groupMembers(pg.namespace, pg.name)
+	countSucceeded := s.countSucceeded()
+	if d := s.gangs.domain(key); d != nil && d.of(pg.requiredTopologyKeys) {
+		result := simulateGang(inDomain(nodeInfos, d), pod, pg, members, countSucceeded, s.args.RoleLabelKey)
+		if result.fits || s.holding(key) > 0 {
+			if !result.fits {
+				result = &capacityState{msg: fmt.Sprintf("podGroup %v/%v does not fit in its topology domain %v anymore: %v",
+					pg.namespace, pg.name, d, result.msg)}
+			}
+			return result
+		}
+	}
+
+	// The nodes holding siblings, the domain must contain them all.
+	siblingNodes := sets.NewString()
+	for _, ni := range nodeInfos {
+		if ni.Node() == nil {
+			continue
+		}
+		for _, pi := range ni.Pods {
+			p := pi.Pod
+			if p.Namespace == pg.namespace && p.Name != pod.Name && p.DeletionTimestamp == nil &&
+				p.Status.Phase != v1.PodSucceeded && p.Status.Phase != v1.PodFailed && s.groupName(p) == pg.name {
+				siblingNodes.Insert(ni.Node().Name)
+			}
+		}
+	}
+	for level := len(pg.requiredTopologyKeys); level > 0; level-- {
+		domains := map[string]*requiredDomain{}
+		nodes := map[string][]*framework.NodeInfo{}
+		for _, ni := range nodeInfos {
+			if ni.Node() == nil {
+				continue
+			}
+			if d, ok := domainOf(ni.Node(), pg.requiredTopologyKeys[:level]); ok {
+				domains[d.String()] = d
+				nodes[d.String()] = append(nodes[d.String()], ni)
+			}
+		}
+		names := make([]string, 0, len(domains))
+		for name := range domains {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			contained := 0
+			for _, ni := range nodes[name] {
+				if siblingNodes.Has(ni.Node().Name) {
+					contained++
+				}
+			}
+			if contained < siblingNodes.Len() {
+				continue
+			}
+			if simulateGang(nodes[name], pod, pg, members, countSucceeded, s.args.RoleLabelKey).fits {
+				s.gangs.setDomain(key, domains[name])
+				return &capacityState{fits: true}
+			}
+		}
+	}
+	return &capacityState{
+		msg: fmt.Sprintf("podGroup %v/%v does not fit in a single topology domain of %v",
+			pg.namespace, pg.name, strings.Join(pg.requiredTopologyKeys, ", ")),
+	}
+}
+
+// inDomain returns the nodes of nodeInfos in d.
+func inDomain(nodeInfos []*framework.NodeInfo, d *requiredDomain) []*framework.NodeInfo {
+	var in []*framework.NodeInfo
+	for _, ni := range nodeInfos {
+		if ni.Node() != nil && d.has(ni.Node()) {
+			in = append(in, ni)
+		}
+	}
+	return in
+}
+
+// Filter keeps the members of a group requiring a topology domain in the
+// domain chosen for it in PreFilter.
+func (s *Sample) Filter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	podGroupName := s.groupName(pod)
+	if podGroupName == "" {
+		return framework.NewStatus(framework.Success, "")
+	}
+	pg, err := s.getPodGroup(pod.Namespace, podGroupName)
+	if err != nil || len(pg.requiredTopologyKeys) == 0 {
+		// No domain to keep to, or PreFilter reported why the group is not
+		// usable.
+		return framework.NewStatus(framework.Success, "")
+	}
+	if nodeInfo.Node() == nil {
+		return framework.NewStatus(framework.Error, "node not found")
+	}
+	d := s.gangs.domain(pod.Namespace + "/" + podGroupName)
+	if d == nil {
+		return framework.NewStatus(framework.Unschedulable,
+			fmt.Sprintf("no topology domain is chosen for podGroup %v/%v yet", pod.Namespace, podGroupName))
+	}
+	if !d.has(nodeInfo.Node()) {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable,
+			fmt.Sprintf("node is outside the topology domain %v of podGroup %v/%v", d, pod.Namespace, podGroupName))
+	}
+	return framework.NewStatus(framework.Success, "")
+}
@@ -0,0 +1,172 @@
+package sample
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const rackKey = "example.com/rack"
+
+// rackNode returns the NodeInfo of a gpu node in rack of zone, with no label
+// for an empty one.
+func rackNode(name, zone, rack, gpus string, pods ...*corev1.Pod) *framework.NodeInfo {
+	ni := gpuNode(name, gpus, pods...)
+	ni.Node().Labels = map[string]string{}
+	if zone != "" {
+		ni.Node().Labels["topology.kubernetes.io/zone"] = zone
+	}
+	if rack != "" {
+		ni.Node().Labels[rackKey] = rack
+	}
+	return ni
+}
+
+func TestPlaceInDomain(t *testing.T) {
+	oneGPU := corev1.ResourceList{gpu: resource.MustParse("1")}
+	pending := func() []*corev1.Pod {
+		return []*corev1.Pod{gangMember("member-0", oneGPU), gangMember("member-1", oneGPU), gangMember("member-2", oneGPU)}
+	}
+	bound := gangMember("member-0", oneGPU)
+	bound.Spec.NodeName = "node1"
+	withBound := []*corev1.Pod{bound, gangMember("member-1", oneGPU), gangMember("member-2", oneGPU)}
+
+	for _, tt := range []struct {
+		name      string
+		nodeInfos []*framework.NodeInfo
+		members   []*corev1.Pod
+		// remembered is the domain chosen before, if any.
+		remembered *requiredDomain
+		fits       bool
+		expected   string
+	}{
+		{
+			name: "narrowest domain first",
+			nodeInfos: []*framework.NodeInfo{
+				rackNode("node1", "a", "r1", "2"),
+				rackNode("node2", "a", "r2", "2"),
+				rackNode("node3", "b", "r3", "4"),
+			},
+			members:  pending(),
+			fits:     true,
+			expected: "topology.kubernetes.io/zone=b,example.com/rack=r3",
+		},
+		{
+			name: "wider domain when no rack fits",
+			nodeInfos: []*framework.NodeInfo{
+				rackNode("node1", "a", "r1", "2"),
+				rackNode("node2", "a", "r2", "2"),
+				rackNode("node3", "b", "r3", "2"),
+			},
+			members:  pending(),
+			fits:     true,
+			expected: "topology.kubernetes.io/zone=a",
+		},
+		{
+			name: "nodes without the keys are left out",
+			nodeInfos: []*framework.NodeInfo{
+				rackNode("node1", "a", "r1", "2"),
+				rackNode("node2", "", "", "4"),
+			},
+			members: pending(),
+		},
+		{
+			name: "the domain holds the placed siblings",
+			nodeInfos: []*framework.NodeInfo{
+				rackNode("node1", "a", "r1", "1", bound),
+				rackNode("node2", "a", "r2", "4"),
+				rackNode("node3", "b", "r3", "4"),
+			},
+			members:  withBound,
+			fits:     true,
+			expected: "topology.kubernetes.io/zone=a",
+		},
+		{
+			name: "the domain of placed members is kept",
+			nodeInfos: []*framework.NodeInfo{
+				rackNode("node1", "a", "r1", "1", bound),
+				rackNode("node2", "a", "r2", "4"),
+				rackNode("node3", "b", "r3", "4"),
+			},
+			members:    withBound,
+			remembered: &requiredDomain{keys: []string{"topology.kubernetes.io/zone", rackKey}, values: []string{"a", "r1"}},
+			expected:   "topology.kubernetes.io/zone=a,example.com/rack=r1",
+		},
+		{
+			name: "another domain once no member is placed",
+			nodeInfos: []*framework.NodeInfo{
+				rackNode("node1", "a", "r1", "1"),
+				rackNode("node3", "b", "r3", "4"),
+			},
+			members:    pending(),
+			remembered: &requiredDomain{keys: []string{"topology.kubernetes.io/zone", rackKey}, values: []string{"a", "r1"}},
+			fits:       true,
+			expected:   "topology.kubernetes.io/zone=b,example.com/rack=r3",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Sample{args: defaultArgs(), gangs: newGangManager(), cmLister: clientv1.NewConfigMapLister(newIndexer(
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "ns"}, Data: map[string]string{
+					minAvailable: "3", requiredTopologyKeys: "topology.kubernetes.io/zone, " + rackKey}},
+			))}
+			addPods(s, tt.members...)
+			if tt.remembered != nil {
+				s.gangs.setDomain("ns/pg", tt.remembered)
+			}
+			pg, err := s.getPodGroup("ns", "pg")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result := s.placeInDomain(tt.nodeInfos, tt.members[len(tt.members)-1], pg)
+			if result.fits != tt.fits {
+				t.Errorf("expected fits %v, got %v: %v", tt.fits, result.fits, result.msg)
+			}
+			got := ""
+			if d := s.gangs.domain("ns/pg"); d != nil {
+				got = d.String()
+			}
+			if got != tt.expected {
+				t.Errorf("expected domain %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFilterDomain(t *testing.T) {
+	s := &Sample{args: defaultArgs(), gangs: newGangManager(), cmLister: clientv1.NewConfigMapLister(newIndexer(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "ns"}, Data: map[string]string{
+			minAvailable: "2", requiredTopologyKeys: "topology.kubernetes.io/zone"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "free", Namespace: "ns"}, Data: map[string]string{minAvailable: "2"}},
+	))}
+	member := gangMember("member-0", nil)
+	free := gangMember("free-0", nil)
+	free.Labels[PodGroupName] = "free"
+	addPods(s, member, free)
+	inZone, outOfZone := rackNode("node1", "a", "", "1"), rackNode("node2", "b", "", "1")
+
+	ctx := context.TODO()
+	if status := s.Filter(ctx, nil, member, inZone); status.Code() != framework.Unschedulable {
+		t.Errorf("expected %v before a domain is chosen, got %v", framework.Unschedulable, status.Code())
+	}
+	s.gangs.setDomain("ns/pg", &requiredDomain{keys: []string{"topology.kubernetes.io/zone"}, values: []string{"a"}})
+	if status := s.Filter(ctx, nil, member, inZone); !status.IsSuccess() {
+		t.Errorf("expected the node in the domain to fit, got %v", status)
+	}
+	if status := s.Filter(ctx, nil, member, outOfZone); status.Code() != framework.UnschedulableAndUnresolvable {
+		t.Errorf("expected %v outside the domain, got %v", framework.UnschedulableAndUnresolvable, status.Code())
+	}
+	if status := s.Filter(ctx, nil, free, outOfZone); !status.IsSuccess() {
+		t.Errorf("expected a group requiring no domain to fit anywhere, got %v", status)
+	}
+
+	// The domain is forgotten with the last member.
+	s.gangs.deletePod(member)
+	if d := s.gangs.domain("ns/pg"); d != nil {
+		t.Errorf("expected the domain to be forgotten, got %v", d)
+	}
+}